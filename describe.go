@@ -0,0 +1,153 @@
+package ecp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Field describes a key of a config, see Describe
+type Field struct {
+	Key     string   // environment key
+	Aliases []string // other names of the key, see the env tag
+	// Go path of the field, Conf.Redis.Port. The element of a list of
+	// sections is Conf.Upstreams[<N>] and the entry of a map of sections
+	// Conf.DB[<KEY>], like in Key.
+	Path string
+	// Section is the prefix of the keys of the section the field is in,
+	// APP_REDIS for APP_REDIS_HOST, the prefix of Describe at the root
+	Section     string
+	Type        reflect.Type
+	Tag         reflect.StructTag // of the struct field, validation tags and all
+	Default     string            // from the default tag, in its canonical form
+	Description string            // from the desc tag, or else the usage tag
+	Required    bool
+	// Optional is true for a pointer, which stays nil without a value,
+	// and for the fields of an optional section, a list or a map of
+	// sections, which only exist once one of their keys is set
+	Optional bool
+	// Separator between the elements of a slice, or the entries of a
+	// map, empty for any other type
+	Separator string
+}
+
+// Describe returns every key of a config, in the order of the fields,
+// with the keys of a list of sections written with an <N> placeholder
+// for the index, and the ones of a map of sections with a <KEY> one. It
+// is what List is made of, for the tools writing documentation.
+func (e *ECP) Describe(config interface{}, prefix ...string) []Field {
+	if len(prefix) == 0 {
+		prefix = []string{""}
+	}
+	return e.keys(config, prefix[0])
+}
+
+// keys walks a config the way Parse does and describes every key in it
+func (e *ECP) keys(config interface{}, prefix string) []Field {
+	value := toValue(config)
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return []Field{}
+	}
+	return e.describe(value, prefix, value.Type().Name(), false, make(map[reflect.Type]bool, 1))
+}
+
+func (e *ECP) describe(configValue reflect.Value, parentName, path string,
+	optional bool, visiting map[reflect.Type]bool) []Field {
+
+	fields := []Field{}
+	configType := configValue.Type()
+
+	// stop a self referencing type from recursing forever
+	if visiting[configType] {
+		return fields
+	}
+	visiting[configType] = true
+	defer delete(visiting, configType)
+
+	for index := 0; index < configValue.NumField(); index++ {
+		if !configType.Field(index).IsExported() {
+			continue
+		}
+		all := e.getAll(getAllOpt{configType, configValue, index, parentName})
+		if all.key == "" {
+			continue
+		}
+		fieldPath := joinPath(path, configType.Field(index).Name)
+		typ := all.value.Type()
+
+		switch {
+		case all.value.Kind() == reflect.Struct && isSection(all.value):
+			prefix := e.key(parentName, all.parent, all.tag)
+			fields = append(fields, e.describe(all.value, prefix, fieldPath, optional, visiting)...)
+
+		case isSection(all.value):
+			// an optional section: describe the keys of the pointed-to
+			// struct, a nil pointer still has all of them
+			prefix := e.key(parentName, all.parent, all.tag)
+			section := all.value
+			if section.IsNil() {
+				section = reflect.New(typ.Elem())
+			}
+			fields = append(fields, e.describe(section.Elem(), prefix, fieldPath, true, visiting)...)
+
+		case isSectionList(typ):
+			prefix := e.key(e.key(parentName, all.parent, all.tag), indexPlaceholder, "")
+			fields = append(fields, e.describe(reflect.New(sectionType(typ)).Elem(), prefix,
+				fmt.Sprintf("%s[%s]", fieldPath, indexPlaceholder), true, visiting)...)
+
+		case isSectionMap(typ):
+			prefix := e.key(e.key(parentName, all.parent, all.tag), keyPlaceholder, "")
+			fields = append(fields, e.describe(reflect.New(sectionType(typ)).Elem(), prefix,
+				fmt.Sprintf("%s[%s]", fieldPath, keyPlaceholder), true, visiting)...)
+
+		case !canSetType(typ) && !unmarshalsText(typ):
+			// arrays, channels... cannot be filled from a string, so
+			// listing a key for them would be misleading
+			continue
+
+		default:
+			description := all.tag.Get("desc")
+			if description == "" {
+				description = all.tag.Get("usage")
+			}
+			fields = append(fields, Field{
+				Key:         all.key,
+				Aliases:     all.aliases,
+				Path:        fieldPath,
+				Section:     parentName,
+				Type:        typ,
+				Tag:         all.tag,
+				Default:     formatDefault(typ, all.defVal),
+				Description: description,
+				Required:    all.required,
+				Optional:    optional || all.value.Kind() == reflect.Ptr,
+				Separator:   e.separator(all.tag, typ),
+			})
+		}
+	}
+
+	return fields
+}
+
+// separator is the one between the elements of a slice or the entries of
+// a map, pointed to or not
+func (e *ECP) separator(tag reflect.StructTag, typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if unmarshalsText(typ) {
+		return ""
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		return e.splitChar(tag)
+	case reflect.Map:
+		entry, _ := e.mapSeparators()
+		return entry
+	}
+	return ""
+}
+
+// Describe returns every key of a config, see ECP.Describe
+func Describe(config interface{}, prefix ...string) []Field {
+	return globalEcp.Describe(config, prefix...)
+}
@@ -0,0 +1,62 @@
+package ecp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	type conf struct {
+		Port    int           `default:"8080" desc:"port to listen on" required:"true"`
+		Timeout time.Duration `default:"90s" usage:"request timeout"`
+		Tags    []string      `sep:","`
+		Labels  map[string]string
+		Retry   *int
+		Host    string `env:"DESC_HOST,DESC_OLD_HOST"`
+		TLS     *struct {
+			Cert string `required:"true"`
+		} `yaml:"tls"`
+		Upstreams []struct{ Host string }
+		DB        map[string]struct{ Port int }
+	}
+
+	fields := Describe(conf{}, "APP")
+	want := []Field{
+		{Key: "APP_PORT", Section: "APP", Path: "conf.Port", Type: reflect.TypeOf(0),
+			Tag:     `default:"8080" desc:"port to listen on" required:"true"`,
+			Default: "8080", Description: "port to listen on", Required: true},
+		{Key: "APP_TIMEOUT", Section: "APP", Path: "conf.Timeout", Type: reflect.TypeOf(time.Duration(0)),
+			Tag:     `default:"90s" usage:"request timeout"`,
+			Default: "90s", Description: "request timeout"},
+		{Key: "APP_TAGS", Section: "APP", Path: "conf.Tags", Type: reflect.TypeOf([]string{}),
+			Tag: `sep:","`, Separator: ","},
+		{Key: "APP_LABELS", Section: "APP", Path: "conf.Labels", Type: reflect.TypeOf(map[string]string{}),
+			Separator: ","},
+		{Key: "APP_RETRY", Section: "APP", Path: "conf.Retry", Type: reflect.TypeOf((*int)(nil)),
+			Optional: true},
+		{Key: "DESC_HOST", Section: "APP", Path: "conf.Host", Type: reflect.TypeOf(""),
+			Tag: `env:"DESC_HOST,DESC_OLD_HOST"`, Aliases: []string{"DESC_OLD_HOST"}},
+		{Key: "APP_TLS_CERT", Section: "APP_TLS", Path: "conf.TLS.Cert", Type: reflect.TypeOf(""),
+			Tag: `required:"true"`, Required: true, Optional: true},
+		{Key: "APP_UPSTREAMS_<N>_HOST", Section: "APP_UPSTREAMS_<N>", Path: "conf.Upstreams[<N>].Host",
+			Type: reflect.TypeOf(""), Optional: true},
+		{Key: "APP_DB_<KEY>_PORT", Section: "APP_DB_<KEY>", Path: "conf.DB[<KEY>].Port",
+			Type: reflect.TypeOf(0), Optional: true},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields: %+v", len(fields), fields)
+	}
+	for i := range want {
+		if want[i].Aliases == nil {
+			want[i].Aliases = []string{}
+		}
+		if !reflect.DeepEqual(fields[i], want[i]) {
+			t.Errorf("got  %+v\nwant %+v", fields[i], want[i])
+		}
+	}
+
+	if len(Describe("not a struct")) != 0 {
+		t.Error("expected no fields")
+	}
+}
@@ -0,0 +1,117 @@
+package ecp
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Markdown documents every key of a config as Markdown tables, one per
+// section: key, type, default, whether it is required and description
+func (e *ECP) Markdown(config interface{}, prefix ...string) string {
+	var b strings.Builder
+	for i, group := range groupBySection(e.Describe(config, prefix...)) {
+		if i != 0 {
+			b.WriteString("\n")
+		}
+		if section := group[0].Section; section != "" {
+			fmt.Fprintf(&b, "### %s\n\n", section)
+		}
+		b.WriteString("| Key | Type | Default | Required | Description |\n")
+		b.WriteString("|-----|------|---------|----------|-------------|\n")
+		for _, f := range group {
+			required := ""
+			if f.Required {
+				required = "yes"
+			}
+			defVal := ""
+			if f.Default != "" {
+				defVal = "`" + f.Default + "`"
+			}
+			fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s |\n", f.Key, f.Type,
+				markdownCell(defVal), required, markdownCell(description(f)))
+		}
+	}
+	return b.String()
+}
+
+// markdownCell keeps a value on one line and out of the next column
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(s)
+}
+
+// Text documents every key of a config as aligned plain text, one block
+// per section, the way a --help lists its flags
+func (e *ECP) Text(config interface{}, prefix ...string) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for i, group := range groupBySection(e.Describe(config, prefix...)) {
+		if i != 0 {
+			fmt.Fprintln(w)
+		}
+		indent := ""
+		if section := group[0].Section; section != "" {
+			fmt.Fprintf(w, "%s:\n", section)
+			indent = "  "
+		}
+		for _, f := range group {
+			details := []string{}
+			if f.Default != "" {
+				details = append(details, fmt.Sprintf("default %s", quoteValue(f.Default)))
+			}
+			if f.Required {
+				details = append(details, "required")
+			}
+			line := strings.Join(strings.Fields(description(f)), " ")
+			if len(details) != 0 {
+				line = strings.TrimSpace(line + " (" + strings.Join(details, ", ") + ")")
+			}
+			if line == "" {
+				fmt.Fprintf(w, "%s%s\t%s\n", indent, f.Key, f.Type)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s\t%s\t%s\n", indent, f.Key, f.Type, line)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
+
+// description is the one of the field, plus its aliases
+func description(f Field) string {
+	if len(f.Aliases) == 0 {
+		return f.Description
+	}
+	aliases := "also read from " + strings.Join(f.Aliases, ", ")
+	if f.Description == "" {
+		return aliases
+	}
+	return f.Description + ", " + aliases
+}
+
+// groupBySection groups the fields of a section together, the sections in
+// the order they come in
+func groupBySection(fields []Field) [][]Field {
+	groups := [][]Field{}
+	index := map[string]int{}
+	for _, f := range fields {
+		i, ok := index[f.Section]
+		if !ok {
+			i = len(groups)
+			index[f.Section] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], f)
+	}
+	return groups
+}
+
+// Markdown documents every key of a config, see ECP.Markdown
+func Markdown(config interface{}, prefix ...string) string {
+	return globalEcp.Markdown(config, prefix...)
+}
+
+// Text documents every key of a config, see ECP.Text
+func Text(config interface{}, prefix ...string) string {
+	return globalEcp.Text(config, prefix...)
+}
@@ -0,0 +1,60 @@
+package ecp
+
+import (
+	"testing"
+	"time"
+)
+
+type docConf struct {
+	Port    int           `default:"8080" desc:"port to listen on" required:"true"`
+	Timeout time.Duration `default:"90s" usage:"request | timeout"`
+	Host    string        `env:"DOC_HOST,DOC_OLD_HOST"`
+	Redis   struct {
+		Addr string `default:"localhost:6379" desc:"address,\nhost:port"`
+	}
+	Upstreams []struct{ Host string }
+}
+
+func TestMarkdown(t *testing.T) {
+	want := "### APP\n\n" +
+		"| Key | Type | Default | Required | Description |\n" +
+		"|-----|------|---------|----------|-------------|\n" +
+		"| `APP_PORT` | `int` | `8080` | yes | port to listen on |\n" +
+		"| `APP_TIMEOUT` | `time.Duration` | `90s` |  | request \\| timeout |\n" +
+		"| `DOC_HOST` | `string` |  |  | also read from DOC_OLD_HOST |\n" +
+		"\n### APP_REDIS\n\n" +
+		"| Key | Type | Default | Required | Description |\n" +
+		"|-----|------|---------|----------|-------------|\n" +
+		"| `APP_REDIS_ADDR` | `string` | `localhost:6379` |  | address, host:port |\n" +
+		"\n### APP_UPSTREAMS_<N>\n\n" +
+		"| Key | Type | Default | Required | Description |\n" +
+		"|-----|------|---------|----------|-------------|\n" +
+		"| `APP_UPSTREAMS_<N>_HOST` | `string` |  |  |  |\n"
+	if got := Markdown(docConf{}, "APP"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	want := "APP:\n" +
+		"  APP_PORT     int            port to listen on (default 8080, required)\n" +
+		"  APP_TIMEOUT  time.Duration  request | timeout (default 90s)\n" +
+		"  DOC_HOST     string         also read from DOC_OLD_HOST\n" +
+		"\n" +
+		"APP_REDIS:\n" +
+		"  APP_REDIS_ADDR  string  address, host:port (default localhost:6379)\n" +
+		"\n" +
+		"APP_UPSTREAMS_<N>:\n" +
+		"  APP_UPSTREAMS_<N>_HOST  string\n"
+	if got := Text(docConf{}, "APP"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// without a prefix, the keys of the root have no heading
+	type flat struct {
+		A string `desc:"first"`
+	}
+	if got := Text(flat{}); got != "A  string  first\n" {
+		t.Errorf("got %q", got)
+	}
+}
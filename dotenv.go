@@ -0,0 +1,359 @@
+package ecp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dotenv holds the variables of a .env file. Its Lookup method is a
+// LookupValueFunc, so it can replace the environment altogether:
+//
+//	env, err := ecp.LoadDotenv()
+//	e := ecp.New()
+//	e.LookupValue = env.Lookup
+//	e.Enumerate = env.Enumerate
+//
+// or be layered in front of it with Over. Enumerate is what finds the
+// entries of a map of sections and the keys written in another case,
+// leaving it to list the environment finds none of the file.
+type Dotenv map[string]string
+
+// ReadDotenv parses the content of a .env file.
+//
+// Every line is a KEY=value pair, optionally preceded by "export". Blank
+// lines and lines starting with # are skipped. A value is either
+//
+//   - unquoted: trimmed, and cut at a # preceded by a space (a comment)
+//   - single quoted: taken literally
+//   - double quoted: may span several lines and understands the Go escape
+//     sequences (\n, \t, \", \\, \u00e9, ...) plus \$ for a literal $
+//
+// ${VAR} and $VAR are replaced in unquoted and double quoted values, by a
+// variable defined earlier in the file or else by the environment.
+//
+// This is the format List writes its values in, so a listing saved to a
+// file reads back into the same values.
+func ReadDotenv(r io.Reader) (Dotenv, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dotenvParser{src: string(content), env: Dotenv{}}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.env, nil
+}
+
+// LoadDotenv reads the .env files in order, a variable defined by a later
+// file overrides the one of an earlier file. Without any file name it
+// reads ".env" in the working directory.
+func LoadDotenv(filenames ...string) (Dotenv, error) {
+	if len(filenames) == 0 {
+		filenames = []string{".env"}
+	}
+
+	env := Dotenv{}
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		content, err := ReadDotenv(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for k, v := range content {
+			env[k] = v
+		}
+	}
+	return env, nil
+}
+
+// Lookup returns the value of a key defined in the file
+func (d Dotenv) Lookup(key string) (string, bool) {
+	v, ok := d[key]
+	return v, ok
+}
+
+// Enumerate returns the keys defined in the file starting with the
+// prefix, sorted
+func (d Dotenv) Enumerate(prefix string) []string {
+	keys := []string{}
+	for k := range d {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Over returns a LookupValueFunc reading the file first and falling back
+// to lookup for the keys the file does not define
+func (d Dotenv) Over(lookup LookupValueFunc) LookupValueFunc {
+	return func(key string) (string, bool) {
+		if v, ok := d[key]; ok {
+			return v, true
+		}
+		return lookup(key)
+	}
+}
+
+type dotenvParser struct {
+	src string
+	pos int
+	env Dotenv
+}
+
+// errorf reports an error at the line of the current position
+func (p *dotenvParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *dotenvParser) parse() error {
+	for p.pos < len(p.src) {
+		p.skipBlank()
+		if p.pos == len(p.src) {
+			break
+		}
+		switch p.src[p.pos] {
+		case '\n', '\r':
+			p.pos++
+			continue
+		case '#':
+			p.skipLine()
+			continue
+		}
+
+		key := p.readKey()
+		if key == "export" && p.pos < len(p.src) && isBlank(p.src[p.pos]) {
+			p.skipBlank()
+			key = p.readKey()
+		}
+		if key == "" {
+			return p.errorf("expected a variable name")
+		}
+
+		p.skipBlank()
+		if p.pos == len(p.src) || p.src[p.pos] != '=' {
+			return p.errorf("expected = after %s", key)
+		}
+		p.pos++
+		p.skipBlank()
+
+		value, err := p.readValue()
+		if err != nil {
+			return err
+		}
+		p.env[key] = value
+	}
+	return nil
+}
+
+func (p *dotenvParser) readValue() (string, error) {
+	if p.pos == len(p.src) {
+		return "", nil
+	}
+
+	switch p.src[p.pos] {
+	case '\'':
+		p.pos++
+		end := strings.IndexByte(p.src[p.pos:], '\'')
+		if end == -1 {
+			return "", p.errorf("unterminated single quoted value")
+		}
+		value := p.src[p.pos : p.pos+end]
+		p.pos += end + 1
+		return value, p.endOfLine()
+
+	case '"':
+		p.pos++
+		value, err := p.readDoubleQuoted()
+		if err != nil {
+			return "", err
+		}
+		return value, p.endOfLine()
+	}
+
+	// KEY=#x is a value, KEY= #x is a comment
+	if p.src[p.pos] == '#' && isBlank(p.src[p.pos-1]) {
+		p.skipLine()
+		return "", nil
+	}
+
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end == -1 {
+		end = len(p.src) - p.pos
+	}
+	raw := p.src[p.pos : p.pos+end]
+	p.pos += end
+
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '#' && isBlank(raw[i-1]) {
+			raw = raw[:i]
+			break
+		}
+	}
+	return p.expand(strings.TrimSpace(raw))
+}
+
+// readDoubleQuoted reads up to the closing quote, which may be lines away
+func (p *dotenvParser) readDoubleQuoted() (string, error) {
+	start := p.pos
+	var b strings.Builder
+	for {
+		rest := p.src[p.pos:]
+		switch {
+		case rest == "":
+			p.pos = start
+			return "", p.errorf("unterminated double quoted value")
+
+		case rest[0] == '"':
+			p.pos++
+			return b.String(), nil
+
+		case strings.HasPrefix(rest, `\$`):
+			b.WriteByte('$')
+			p.pos += 2
+
+		case rest[0] == '$':
+			value, n, err := p.variable(rest)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			p.pos += n
+
+		default:
+			r, _, tail, err := strconv.UnquoteChar(rest, '"')
+			if err != nil {
+				return "", p.errorf("bad escape sequence in %q", firstLine(rest))
+			}
+			b.WriteRune(r)
+			p.pos += len(rest) - len(tail)
+		}
+	}
+}
+
+// expand replaces the variables of an unquoted value
+func (p *dotenvParser) expand(v string) (string, error) {
+	if !strings.Contains(v, "$") {
+		return v, nil
+	}
+
+	var b strings.Builder
+	for v != "" {
+		i := strings.IndexByte(v, '$')
+		if i == -1 {
+			b.WriteString(v)
+			break
+		}
+		b.WriteString(v[:i])
+		value, n, err := p.variable(v[i:])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		v = v[i+n:]
+	}
+	return b.String(), nil
+}
+
+// variable resolves the ${VAR} or $VAR at the start of s and returns its
+// value and the length of the reference. A $ not followed by a name is
+// just a dollar sign.
+func (p *dotenvParser) variable(s string) (string, int, error) {
+	if strings.HasPrefix(s, "${") {
+		end := strings.IndexByte(s, '}')
+		if end == -1 {
+			return "", 0, p.errorf("unterminated variable in %q", firstLine(s))
+		}
+		return p.resolve(s[2:end]), end + 1, nil
+	}
+
+	n := 1
+	for n < len(s) && isNameByte(s[n], n == 1) {
+		n++
+	}
+	if n == 1 {
+		return "$", 1, nil
+	}
+	return p.resolve(s[1:n]), n, nil
+}
+
+func (p *dotenvParser) resolve(name string) string {
+	if v, ok := p.env[name]; ok {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// endOfLine accepts blanks and a comment after a quoted value
+func (p *dotenvParser) endOfLine() error {
+	p.skipBlank()
+	if p.pos == len(p.src) {
+		return nil
+	}
+	switch p.src[p.pos] {
+	case '\n', '\r', '#':
+		p.skipLine()
+		return nil
+	}
+	return p.errorf("unexpected %q after a quoted value", firstLine(p.src[p.pos:]))
+}
+
+func (p *dotenvParser) readKey() string {
+	start := p.pos
+	for p.pos < len(p.src) && isKeyByte(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *dotenvParser) skipBlank() {
+	for p.pos < len(p.src) && isBlank(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *dotenvParser) skipLine() {
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end == -1 {
+		p.pos = len(p.src)
+		return
+	}
+	p.pos += end + 1
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+func isBlank(c byte) bool { return c == ' ' || c == '\t' }
+
+func isKeyByte(c byte) bool {
+	return isNameByte(c, false) || c == '.' || c == '-'
+}
+
+// isNameByte reports whether c may appear in a variable name, a name does
+// not start with a digit
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package ecp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadDotenv(t *testing.T) {
+	withEnv(t, "DOTENV_FROM_ENV", "outside")
+
+	env, err := ReadDotenv(strings.NewReader(`
+# a comment
+PLAIN=value
+export EXPORTED = spaced value   # trailing comment
+HASH=a#b
+EMPTY=
+EMPTY_COMMENT= # nothing here
+SINGLE='keep $PLAIN \n as is'
+DOUBLE="tab\there \"quoted\" \$PLAIN"
+MULTI="line one
+line two"
+UNICODE="café"
+REF=${PLAIN}-$PLAIN
+QUOTED_REF="${DOUBLE_MISSING}x ${DOTENV_FROM_ENV}"
+CRLF=windows` + "\r\n" + `lower.case-key=ok
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"PLAIN":          "value",
+		"EXPORTED":       "spaced value",
+		"HASH":           "a#b",
+		"EMPTY":          "",
+		"EMPTY_COMMENT":  "",
+		"SINGLE":         `keep $PLAIN \n as is`,
+		"DOUBLE":         "tab\there \"quoted\" $PLAIN",
+		"MULTI":          "line one\nline two",
+		"UNICODE":        "café",
+		"REF":            "value-value",
+		"QUOTED_REF":     "x outside",
+		"CRLF":           "windows",
+		"lower.case-key": "ok",
+	}
+	for k, v := range want {
+		if got, ok := env.Lookup(k); !ok || got != v {
+			t.Errorf("%s: got %q (%v), want %q", k, got, ok, v)
+		}
+	}
+	if len(env) != len(want) {
+		t.Errorf("unexpected variables: %v", env)
+	}
+}
+
+func TestReadDotenvErrors(t *testing.T) {
+	for _, content := range []string{
+		"NOEQUAL",
+		"=value",
+		`A="unterminated`,
+		"A='unterminated",
+		`A="bad \q escape"`,
+		`A="x" trailing`,
+		"A=${UNTERMINATED",
+	} {
+		if _, err := ReadDotenv(strings.NewReader(content)); err == nil {
+			t.Errorf("%q should not parse", content)
+		}
+	}
+
+	_, err := ReadDotenv(strings.NewReader("A=1\nB=2\nC"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("the error should point at line 3: %v", err)
+	}
+}
+
+// whatever List writes, ReadDotenv reads back
+func TestDotenvListRoundTrip(t *testing.T) {
+	type conf struct {
+		Plain  string `default:"plain"`
+		Spaces string `default:" a  b "`
+		Quotes string `default:"it's \"quoted\""`
+		Dollar string `default:"$HOME and ${HOME}"`
+		Lines  string `default:"a\nb\tc\\d"`
+		Hash   string `default:"#not a comment"`
+		Empty  string
+	}
+
+	env, err := ReadDotenv(strings.NewReader(strings.Join(List(conf{}), "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := New()
+	e.LookupValue = env.Lookup
+	c := &conf{}
+	if err := e.Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	want := conf{}
+	if err := Parse(&want); err != nil {
+		t.Fatal(err)
+	}
+	if *c != want {
+		t.Errorf("got %+v, want %+v", *c, want)
+	}
+}
+
+func TestLoadDotenv(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	os.WriteFile(base, []byte("HOST=base\nPORT=80\n"), 0o600)
+	os.WriteFile(local, []byte("PORT=8080\n"), 0o600)
+
+	env, err := LoadDotenv(base, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["HOST"] != "base" || env["PORT"] != "8080" {
+		t.Errorf("a later file should override an earlier one: %v", env)
+	}
+
+	if _, err := LoadDotenv(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	t.Run("layered over the environment", func(t *testing.T) {
+		withEnv(t, "PORT", "1")
+		withEnv(t, "NAME", "from-env")
+
+		e := New()
+		e.LookupValue = env.Over(e.LookupValue)
+		c := &struct {
+			Host string
+			Port int
+			Name string
+		}{}
+		if err := e.Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Host != "base" || c.Port != 8080 || c.Name != "from-env" {
+			t.Errorf("got %+v", c)
+		}
+	})
+}
@@ -13,18 +13,66 @@ import (
 // default behaviour has to be changed, or use the package level Parse,
 // List and Get functions to work with the default one.
 type ECP struct {
-	// BuildKey builds the environment key of a field
+	// BuildKey builds the environment key of a field. Left nil, as New
+	// does, the keys are written in Advance.KeyStyle.
 	BuildKey BuildKeyFunc
 	// LookupValue returns the value of a key and whether it exists
 	LookupValue LookupValueFunc
+	// Enumerate lists the keys LookupValue knows, which is how the keys
+	// of a map of sections are found
+	Enumerate EnumerateFunc
+	// Sources, when set, are looked up instead of LookupValue, in order,
+	// see Advance.Precedence
+	Sources []Source
 
 	Advance AdvanceConfig
 }
 
 // AdvanceConfig holds the optional knobs of an ECP
 type AdvanceConfig struct {
-	SplitChar string // split slice
-	SetValue  SetValueFunc
+	SplitChar    string // split slice
+	MapSplitChar string // split map entries
+	MapPairChar  string // split a map entry into its key and value
+	// QuotedSlices lets a slice element hold the separator, quoted or
+	// escaped: "a b" c\ d
+	QuotedSlices bool
+	// JSONSlices also takes a slice value written as a JSON array,
+	// ["a b","c"]
+	JSONSlices bool
+	SetValue   SetValueFunc
+	// KeyStyle is the way the default BuildKey names the keys, LOGLEVEL,
+	// LOG_LEVEL or log-level
+	KeyStyle KeyStyle
+	// Separator goes between the prefix, the sections and the field of a
+	// key, "_" by default ("-" for KebabKeys). A "__" leaves "_" free for
+	// the names themselves: APP__HTTP_SERVER__READ_TIMEOUT.
+	Separator string
+	// IgnoreCase makes app_port a value for APP_PORT, and NormalizeKeys
+	// app-port or app.port. Both only apply to the sources that can
+	// enumerate their keys. Without Sources, the keys are the ones of
+	// Enumerate, which has to list the keys of LookupValue: a Dotenv
+	// needs both its Lookup and its Enumerate.
+	IgnoreCase    bool
+	NormalizeKeys bool
+	// Precedence between the Sources defining the same key
+	Precedence Precedence
+	// CollectErrors makes Parse go on after a value failed to convert,
+	// filling every field it can and returning all the failures at once
+	// as Errors
+	CollectErrors bool
+	// OnResolve, when set, is told the source every key Parse takes a
+	// value from was found in
+	OnResolve func(key, source string)
+	// OnDeprecated, when set, is told every time a value is read from an
+	// alias of a key, `env:"DATABASE_HOST,DB_HOST"` reading DB_HOST
+	OnDeprecated func(alias, key string)
+	// Strict makes Parse with a prefix fail on the variables starting
+	// with it that no field is bound to, APP_REDIS_HSOT for instance,
+	// suggesting the closest known key
+	Strict bool
+	// OnUnknown, when set, is told every such variable along with the
+	// suggestion, empty when no key is close, whether Strict is set or not
+	OnUnknown func(key, suggestion string)
 }
 
 var globalEcp = New()
@@ -32,10 +80,12 @@ var globalEcp = New()
 // New ecp object
 func New() *ECP {
 	return &ECP{
-		BuildKey:    buildKeyFromEnv,
 		LookupValue: lookupValueFromEnv,
+		Enumerate:   enumerateEnv,
 		Advance: AdvanceConfig{
-			SplitChar: space,
+			SplitChar:    space,
+			MapSplitChar: comma,
+			MapPairChar:  equal,
 		},
 	}
 }
@@ -43,9 +93,16 @@ func New() *ECP {
 // Parse the configuration through environments starting with the
 // prefix (or not), see the package level Parse for the details
 func (e *ECP) Parse(config interface{}, prefix ...string) error {
+	return e.parse(config, prefix, nil)
+}
+
+func (e *ECP) parse(config interface{}, prefix []string, report *[]Resolution) error {
 	if len(prefix) == 0 {
 		prefix = []string{""}
 	}
+	if e.Advance.IgnoreCase || e.Advance.NormalizeKeys {
+		e = e.folded()
+	}
 
 	// catch the classic Parse(config) instead of Parse(&config): without
 	// a pointer every field is read-only, so Parse would report success
@@ -55,8 +112,44 @@ func (e *ECP) Parse(config interface{}, prefix ...string) error {
 		return fmt.Errorf("config must be a pointer to a struct, got %s", value.Type())
 	}
 
-	_, err := e.rangeOver(roOption{target: config, setDef: true, prefix: prefix[0]})
-	return err
+	opts := roOption{
+		target: config,
+		setDef: true,
+		prefix: prefix[0],
+		report: report,
+	}
+	var errs, missing []error
+	if e.Advance.CollectErrors {
+		opts.errs = &errs
+	}
+	opts.missing = &missing
+
+	if _, err := e.rangeOver(opts); err != nil {
+		return err
+	}
+	// the root is validated last, after every section in it
+	root := toValue(config)
+	if err := e.validateSection(opts, root, prefix[0], root.Type().Name(), 0); err != nil {
+		return err
+	}
+	if prefix[0] != "" && (e.Advance.Strict || e.Advance.OnUnknown != nil) {
+		unknown, suggestions := e.unknownKeys(config, prefix[0])
+		for i, key := range unknown {
+			if e.Advance.OnUnknown != nil {
+				e.Advance.OnUnknown(key, suggestions[i])
+			}
+			if e.Advance.Strict {
+				_, source, _ := e.lookup(key)
+				missing = append(missing, unknownError(key, suggestions[i], source))
+			}
+		}
+	}
+	// every missing or unknown key is reported, not only the first one,
+	// along with the rules that failed
+	if errs = append(errs, missing...); len(errs) != 0 {
+		return Errors(errs)
+	}
+	return nil
 }
 
 // List all the config environments, see the package level List for
@@ -65,68 +158,24 @@ func (e *ECP) List(config interface{}, prefix ...string) []string {
 	if len(prefix) == 0 {
 		prefix = []string{""}
 	}
-	return e.list(config, prefix[0], make(map[reflect.Type]bool, 1))
-}
-
-func (e *ECP) list(config interface{}, parentName string,
-	visiting map[reflect.Type]bool) []string {
 
 	list := []string{}
-
-	configValue := toValue(config)
-	if !configValue.IsValid() || configValue.Kind() != reflect.Struct {
-		return list
-	}
-	configType := configValue.Type()
-
-	// stop a self referencing type from recursing forever
-	if visiting[configType] {
-		return list
-	}
-	visiting[configType] = true
-	defer delete(visiting, configType)
-
-	for index := 0; index < configValue.NumField(); index++ {
-		if !configType.Field(index).IsExported() {
-			continue
-		}
-		all := e.getAll(getAllOpt{configType, configValue, index, parentName})
-		if all.key == "" {
-			continue
-		}
-		switch {
-		case all.value.Kind() == reflect.Struct:
-			prefix := e.BuildKey(parentName, all.parent, all.tag)
-			list = append(list, e.list(all.value, prefix, visiting)...)
-
-		case isSection(all.value):
-			// an optional section: list the keys of the pointed-to
-			// struct, a nil pointer still has all of them
-			prefix := e.BuildKey(parentName, all.parent, all.tag)
-			section := all.value
-			if section.IsNil() {
-				section = reflect.New(all.value.Type().Elem())
-			}
-			list = append(list, e.list(section.Elem(), prefix, visiting)...)
-
-		case !canSetKind(all.value.Kind()):
-			// maps, arrays, channels... cannot be filled from a string,
-			// so listing a key for them would be misleading
-			continue
-
-		default:
-			list = append(list, fmt.Sprintf("%s=%s", all.key, quoteValue(all.defVal)))
+	for _, f := range e.keys(config, prefix[0]) {
+		item := fmt.Sprintf("%s=%s", f.Key, quoteValue(f.Default))
+		if f.Required {
+			item += " # required"
 		}
+		list = append(list, item)
 	}
-
 	return list
 }
 
 // quoteValue quotes a default value that would not survive a round trip
-// through a shell or an env file unquoted
+// through a shell or an env file unquoted. A $ is escaped too, both a
+// shell and ReadDotenv would expand it otherwise.
 func quoteValue(v string) string {
 	if strings.ContainsAny(v, " \t\r\n\"'\\`$") {
-		return strconv.Quote(v)
+		return strings.ReplaceAll(strconv.Quote(v), "$", `\$`)
 	}
 	return v
 }
@@ -134,8 +183,10 @@ func quoteValue(v string) string {
 // List all the config environments.
 //
 // The value of each key is the one from the "default" tag, empty if the
-// field has no default. Fields tagged with `env:"-"`, `yaml:"-"` or
-// `json:"-"` are skipped.
+// field has no default, written the way the field's TextMarshaler would
+// write it if it has one. A required key is followed by a "# required"
+// comment, which both a shell and ReadDotenv ignore. Fields tagged with
+// `env:"-"`, `yaml:"-"` or `json:"-"` are skipped.
 func List(config interface{}, prefix ...string) []string {
 	return globalEcp.List(config, prefix...)
 }
@@ -163,6 +214,14 @@ func List(config interface{}, prefix ...string) []string {
 //	c := &config{}
 //
 // Slice values are separated by Advance.SplitChar, a space by default.
+// Map values are written as "a=1,b=2", see Advance.MapSplitChar and
+// Advance.MapPairChar.
+//
+// Once filled, every section implementing Validator is validated, from
+// the innermost one up to the config itself.
+//
+// A field tagged `required:"true"` (or `env:"KEY,required"`) must end up
+// with a value, Parse lists every required key left empty in its error.
 //
 // config must be a pointer to a struct, otherwise Parse returns an error
 // instead of silently doing nothing.
@@ -1,6 +1,7 @@
 package ecp
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -125,7 +126,8 @@ func TestList(t *testing.T) {
 
 	t.Run("with get key", func(t *testing.T) {
 		// reset get key function
-		defer func() { globalEcp.BuildKey = buildKeyFromEnv }()
+		buildKey := globalEcp.BuildKey
+		defer func() { globalEcp.BuildKey = buildKey }()
 
 		globalEcp.BuildKey = func(parentName, structName string, tag reflect.StructTag) (key string) {
 			return strings.ToLower(parentName) + "." + strings.ToLower(structName)
@@ -260,6 +262,7 @@ func TestParseDefault(t *testing.T) {
 func TestGetKeyLookupValue(t *testing.T) {
 	config := configType{}
 
+	buildKey := globalEcp.BuildKey
 	globalEcp.BuildKey = func(parentName, structName string, tag reflect.StructTag) (key string) {
 		if parentName != "" {
 			return parentName + "." + structName
@@ -269,7 +272,7 @@ func TestGetKeyLookupValue(t *testing.T) {
 	// both hooks have to be restored, leaving the lookup mock behind
 	// makes every test running after this one read from it
 	defer func() {
-		globalEcp.BuildKey = buildKeyFromEnv
+		globalEcp.BuildKey = buildKey
 		globalEcp.LookupValue = lookupValueFromEnv
 	}()
 
@@ -338,3 +341,114 @@ func ExampleList() {
 	// env AGE=
 	// env NAME=
 }
+
+func TestRequired(t *testing.T) {
+	type db struct {
+		URL  string `env:"REQ_DB_URL,required"`
+		Pool int    `default:"4"`
+	}
+	type conf struct {
+		Token    string `required:"true"`
+		Name     string `required:"true" default:"app"`
+		Port     int    `env:"REQ_PORT,required"`
+		Optional string `required:"false"`
+		DB       db
+		Replica  *db `yaml:"replica"`
+		Cache    *struct {
+			Host string `env:"REQ_CACHE_HOST" required:"true"`
+		}
+	}
+
+	err := Parse(&conf{})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected every missing key, got %v", err)
+	}
+	// the keys of the untouched optional sections are not missing, the
+	// ones of a section that was filled in are
+	got := []string{}
+	for _, err := range errs {
+		var perr *ParseError
+		if !errors.As(err, &perr) || !errors.Is(err, ErrRequired) {
+			t.Errorf("unexpected error %v", err)
+			continue
+		}
+		got = append(got, perr.Key)
+	}
+	if strings.Join(got, " ") != "TOKEN REQ_PORT REQ_DB_URL REQ_DB_URL" {
+		t.Errorf("missing keys: %v", got)
+	}
+
+	withEnv(t, "TOKEN", "secret")
+	withEnv(t, "REQ_PORT", "0")
+	withEnv(t, "REQ_DB_URL", "postgres://")
+	c := &conf{}
+	if err := Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Token != "secret" || c.DB.URL != "postgres://" || c.Cache != nil {
+		t.Errorf("got %+v", c)
+	}
+
+	t.Run("listed", func(t *testing.T) {
+		list := strings.Join(List(conf{}), "\n")
+		for _, want := range []string{"TOKEN= # required", "NAME=app # required",
+			"REQ_PORT= # required", "OPTIONAL=\n"} {
+			if !strings.Contains(list, want) {
+				t.Errorf("missing %q in %s", want, list)
+			}
+		}
+	})
+}
+
+func TestAliases(t *testing.T) {
+	type conf struct {
+		Host string `env:"ALIAS_DATABASE_HOST,ALIAS_DB_HOST,ALIAS_PG_HOST" default:"localhost"`
+		Port int    `env:"ALIAS_DATABASE_PORT,ALIAS_DB_PORT,required"`
+	}
+
+	withEnv(t, "ALIAS_DB_HOST", "old")
+	withEnv(t, "ALIAS_PG_HOST", "older")
+	withEnv(t, "ALIAS_DB_PORT", "5432")
+
+	deprecated := []string{}
+	e := New()
+	e.Advance.OnDeprecated = func(alias, key string) {
+		deprecated = append(deprecated, alias+"->"+key)
+	}
+	c := &conf{}
+	if err := e.Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "old" || c.Port != 5432 {
+		t.Errorf("got %+v", c)
+	}
+	if strings.Join(deprecated, " ") != "ALIAS_DB_HOST->ALIAS_DATABASE_HOST ALIAS_DB_PORT->ALIAS_DATABASE_PORT" {
+		t.Errorf("deprecated: %v", deprecated)
+	}
+
+	// the primary name wins
+	withEnv(t, "ALIAS_DATABASE_HOST", "new")
+	deprecated = deprecated[:0]
+	if err := e.Parse(c); err != nil || c.Host != "new" {
+		t.Errorf("got %+v, %v", c, err)
+	}
+	if strings.Join(deprecated, " ") != "ALIAS_DB_PORT->ALIAS_DATABASE_PORT" {
+		t.Errorf("deprecated: %v", deprecated)
+	}
+
+	// a bad value is reported under the name it was read from
+	withEnv(t, "ALIAS_DB_PORT", "x")
+	var perr *ParseError
+	if err := e.Parse(&conf{}); !errors.As(err, &perr) || perr.Key != "ALIAS_DB_PORT" {
+		t.Errorf("got %v", err)
+	}
+
+	if v, err := e.GetString(c, "ALIAS_PG_HOST"); err != nil || v != "new" {
+		t.Errorf("get by alias: %v %v", v, err)
+	}
+	if list := strings.Join(e.List(conf{}), " "); list !=
+		"ALIAS_DATABASE_HOST=localhost ALIAS_DATABASE_PORT= # required" {
+		t.Errorf("list: %s", list)
+	}
+}
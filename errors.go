@@ -0,0 +1,89 @@
+package ecp
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrNotFound is the error of the Get helpers for a key no field is bound
+// to, wrapped in a ParseError
+var ErrNotFound = errors.New("not found")
+
+// ErrRequired is the error of a required key left without a value,
+// wrapped in a ParseError
+var ErrRequired = errors.New("required but not set")
+
+// ErrUnknown is the error of a variable under the prefix no field is bound
+// to, see AdvanceConfig.Strict, wrapped in a ParseError
+var ErrUnknown = errors.New("unknown key")
+
+// ParseError is the error of a single key, returned by Parse for a value
+// that failed to convert and by the Get helpers
+type ParseError struct {
+	Key    string       // environment key
+	Path   string       // Go path of the field, Conf.Redis.Port
+	Type   reflect.Type // type of the field, nil when there is no field
+	Value  string       // raw value, empty for the Get helpers
+	Source string       // name of the source of Value, empty for a default
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	// the root of a config parsed without a prefix has no key, and an
+	// anonymous struct no path either
+	var subject string
+	switch {
+	case e.Key == "":
+		subject = e.Path
+	case e.Path == "":
+		subject = "key " + e.Key
+	default:
+		subject = "key " + e.Key + " (" + e.Path + ")"
+	}
+	if subject == "" {
+		return e.Err.Error()
+	}
+	return subject + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is every error found by a Parse that went on after the first
+// one, see AdvanceConfig.CollectErrors. errors.Is and errors.As look
+// through all of them, the way they do for an errors.Join error, with
+// the Go versions before 1.20 too.
+type Errors []error
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, err := range es {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether one of the errors matches target, for errors.Is
+// before Go 1.20, which does not know about Unwrap() []error
+func (es Errors) Is(target error) bool {
+	for _, err := range es {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target, for errors.As
+// before Go 1.20
+func (es Errors) As(target interface{}) bool {
+	for _, err := range es {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+//go:build go1.20
+
+package ecp
+
+// Unwrap returns the errors, for errors.Is, errors.As and the other
+// helpers of Go 1.20 that know about Unwrap() []error. Older versions
+// vet this signature as a mistake.
+func (es Errors) Unwrap() []error {
+	return es
+}
@@ -0,0 +1,132 @@
+package ecp
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectErrors(t *testing.T) {
+	type conf struct {
+		Port    int           `env:"COLLECT_PORT"`
+		Name    string        `env:"COLLECT_NAME"`
+		Timeout time.Duration `env:"COLLECT_TIMEOUT"`
+		Ratio   float64       `env:"COLLECT_RATIO" default:"0.5"`
+		Sub     struct {
+			Ints []int `env:"COLLECT_INTS"`
+		}
+	}
+
+	withEnv(t, "COLLECT_PORT", "eighty")
+	withEnv(t, "COLLECT_NAME", "app")
+	withEnv(t, "COLLECT_TIMEOUT", "soon")
+	withEnv(t, "COLLECT_INTS", "1 two")
+
+	// the first error stops Parse by default
+	if err := Parse(&conf{}); err == nil || strings.Contains(err.Error(), "COLLECT_TIMEOUT") {
+		t.Errorf("expected only the first error: %v", err)
+	}
+
+	e := New()
+	e.Advance.CollectErrors = true
+	c := &conf{}
+	err := e.Parse(c)
+
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", err)
+	}
+	for _, key := range []string{"COLLECT_PORT", "COLLECT_TIMEOUT", "COLLECT_INTS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("%s is missing from %v", key, err)
+		}
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("errors.As should find the wrapped conversion error: %v", err)
+	}
+
+	// the valid fields are still filled, the broken ones left alone
+	if c.Name != "app" || c.Ratio != 0.5 || c.Port != 0 || c.Sub.Ints != nil {
+		t.Errorf("got %+v", c)
+	}
+
+	// and nothing to report is no error at all
+	if err := e.Parse(&struct{ Name string }{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	type redis struct {
+		Port int `env:"PERR_PORT"`
+	}
+	type Conf struct {
+		Redis redis
+		Ratio float64 `default:"half"`
+	}
+
+	withEnv(t, "PERR_PORT", "eighty")
+	err := Parse(&Conf{})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a *ParseError, got %T %v", err, err)
+	}
+	if perr.Key != "PERR_PORT" || perr.Path != "Conf.Redis.Port" ||
+		perr.Type != reflect.TypeOf(0) || perr.Value != "eighty" || perr.Source != "env" {
+		t.Errorf("got %+v", perr)
+	}
+	if !strings.Contains(err.Error(), "Conf.Redis.Port") {
+		t.Errorf("the message should name the field: %v", err)
+	}
+
+	// a default value has no source
+	os.Unsetenv("PERR_PORT")
+	if err := Parse(&Conf{}); !errors.As(err, &perr) || perr.Key != "RATIO" ||
+		perr.Value != "half" || perr.Source != "" {
+		t.Errorf("got %+v", perr)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	type Conf struct {
+		Name string `default:"x"`
+	}
+	c := &Conf{}
+
+	_, err := Get(c, "NOPE")
+	var perr *ParseError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &perr) || perr.Key != "NOPE" {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	_, err = GetInt64(c, "NAME")
+	if !errors.As(err, &perr) || errors.Is(err, ErrNotFound) ||
+		perr.Path != "Conf.Name" || perr.Type.Kind() != reflect.String {
+		t.Errorf("expected a type mismatch error, got %+v", perr)
+	}
+}
+
+// the methods errors.Is and errors.As call before Go 1.20, which does not
+// look through Unwrap() []error
+func TestErrorsIsAs(t *testing.T) {
+	errs := Errors{
+		errors.New("first"),
+		&ParseError{Key: "PORT", Err: ErrRequired},
+	}
+	if !errs.Is(ErrRequired) || errs.Is(ErrNotFound) {
+		t.Error("Is should look through every error")
+	}
+	var perr *ParseError
+	if !errs.As(&perr) || perr.Key != "PORT" {
+		t.Errorf("As: got %v", perr)
+	}
+	var numErr *strconv.NumError
+	if errs.As(&numErr) {
+		t.Error("As should not match")
+	}
+}
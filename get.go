@@ -1,36 +1,58 @@
 package ecp
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 )
 
-// getValue looks up a field by the environment key it is bound to. The
-// optional prefix must match the one Parse was called with, since that is
-// what the key names are built from.
-func (e *ECP) getValue(config interface{}, keyName string, prefix ...string) (reflect.Value, error) {
+// getValue looks up a field by the environment key it is bound to, and
+// returns its Go path along with it. The optional prefix must match the
+// one Parse was called with, since that is what the key names are built
+// from.
+//
+// Every error it returns, and the ones of the helpers built on it, is a
+// *ParseError, wrapping ErrNotFound when no field is bound to the key.
+func (e *ECP) getValue(config interface{}, keyName string, prefix ...string) (reflect.Value, string, error) {
 	if len(prefix) == 0 {
 		prefix = []string{""}
 	}
 
-	v, err := e.rangeOver(roOption{target: config, find: keyName, prefix: prefix[0]})
+	var path string
+	v, err := e.rangeOver(roOption{
+		target:    config,
+		find:      keyName,
+		prefix:    prefix[0],
+		foundPath: &path,
+	})
 	if err != nil {
-		return reflect.Value{}, err
+		return reflect.Value{}, "", &ParseError{Key: keyName, Err: err}
 	}
 
 	if !v.IsValid() {
-		return reflect.Value{}, fmt.Errorf("key %s not found", keyName)
+		return reflect.Value{}, "", &ParseError{Key: keyName, Err: ErrNotFound}
 	}
 
 	if !v.CanInterface() {
-		return reflect.Value{}, fmt.Errorf("bad structure field %s", keyName)
+		return reflect.Value{}, "", &ParseError{Key: keyName, Path: path, Type: v.Type(),
+			Err: errors.New("bad structure field")}
+	}
+	return v, path, nil
+}
+
+// mismatch is the error of a Get helper finding a value of another kind
+func mismatch(keyName, path string, v reflect.Value, want string) error {
+	return &ParseError{
+		Key:  keyName,
+		Path: path,
+		Type: v.Type(),
+		Err:  fmt.Errorf("value is not %s, it's %s", want, v.Kind()),
 	}
-	return v, nil
 }
 
 // Get the value of the keyName in that struct
 func (e *ECP) Get(config interface{}, keyName string, prefix ...string) (interface{}, error) {
-	v, err := e.getValue(config, keyName, prefix...)
+	v, _, err := e.getValue(config, keyName, prefix...)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +62,7 @@ func (e *ECP) Get(config interface{}, keyName string, prefix ...string) (interfa
 
 // GetBool returns bool
 func (e *ECP) GetBool(config interface{}, keyName string, prefix ...string) (bool, error) {
-	v, err := e.getValue(config, keyName, prefix...)
+	v, path, err := e.getValue(config, keyName, prefix...)
 	if err != nil {
 		return false, err
 	}
@@ -48,12 +70,12 @@ func (e *ECP) GetBool(config interface{}, keyName string, prefix ...string) (boo
 	if v.Kind() == reflect.Bool {
 		return v.Bool(), nil
 	}
-	return false, fmt.Errorf("value is not bool, it's %s", v.Kind())
+	return false, mismatch(keyName, path, v, "bool")
 }
 
 // GetInt64 returns int64
 func (e *ECP) GetInt64(config interface{}, keyName string, prefix ...string) (int64, error) {
-	v, err := e.getValue(config, keyName, prefix...)
+	v, path, err := e.getValue(config, keyName, prefix...)
 	if err != nil {
 		return -1, err
 	}
@@ -63,12 +85,12 @@ func (e *ECP) GetInt64(config interface{}, keyName string, prefix ...string) (in
 		return v.Int(), nil
 	}
 
-	return -1, fmt.Errorf("value is %s", v.Kind())
+	return -1, mismatch(keyName, path, v, "int")
 }
 
 // GetString returns string
 func (e *ECP) GetString(config interface{}, keyName string, prefix ...string) (string, error) {
-	v, err := e.getValue(config, keyName, prefix...)
+	v, path, err := e.getValue(config, keyName, prefix...)
 	if err != nil {
 		return "", err
 	}
@@ -76,12 +98,12 @@ func (e *ECP) GetString(config interface{}, keyName string, prefix ...string) (s
 	if v.Kind() == reflect.String {
 		return v.String(), nil
 	}
-	return "", fmt.Errorf("value is not string, it's %s", v.Kind())
+	return "", mismatch(keyName, path, v, "string")
 }
 
 // GetFloat64 returns float64
 func (e *ECP) GetFloat64(config interface{}, keyName string, prefix ...string) (float64, error) {
-	v, err := e.getValue(config, keyName, prefix...)
+	v, path, err := e.getValue(config, keyName, prefix...)
 	if err != nil {
 		return -1, err
 	}
@@ -90,7 +112,7 @@ func (e *ECP) GetFloat64(config interface{}, keyName string, prefix ...string) (
 	case reflect.Float32, reflect.Float64:
 		return v.Float(), nil
 	}
-	return -1, fmt.Errorf("value is %s", v.Kind())
+	return -1, mismatch(keyName, path, v, "float")
 }
 
 // Get the value of the keyName in that struct
@@ -0,0 +1,113 @@
+package ecp
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// KeyStyle is the way the default BuildKey writes the name of a field
+type KeyStyle int
+
+const (
+	// LegacyKeys upper cases the names as they are: LogLevel is LOGLEVEL
+	LegacyKeys KeyStyle = iota
+	// SnakeKeys splits the names into words: LogLevel is LOG_LEVEL and
+	// HTTPServer is HTTP_SERVER
+	SnakeKeys
+	// KebabKeys writes the words in lower case joined with dashes, the
+	// way flags are: log-level, redis-http-server
+	KebabKeys
+)
+
+// key builds the key of a field with BuildKey, or with buildKey when it
+// is nil. The default is looked up on the parser it is called on, so a
+// copy of a parser writes the keys of its own Advance.
+func (e *ECP) key(structure, field string, tag reflect.StructTag) string {
+	if e.BuildKey == nil {
+		return e.buildKey(structure, field, tag)
+	}
+	return e.BuildKey(structure, field, tag)
+}
+
+// buildKey is the default BuildKey, writing keys in Advance.KeyStyle with
+// Advance.Separator between the segments. An env tag is taken as is,
+// whatever the style.
+func (e *ECP) buildKey(structure, field string, tag reflect.StructTag) string {
+	sep := e.Advance.Separator
+	switch e.Advance.KeyStyle {
+	case SnakeKeys:
+		if sep == "" {
+			sep = "_"
+		}
+		return styledKey(structure, field, tag, "_", sep, strings.ToUpper)
+	case KebabKeys:
+		if sep == "" {
+			sep = "-"
+		}
+		return styledKey(structure, field, tag, "-", sep, strings.ToLower)
+	}
+	if sep == "" {
+		sep = "_"
+	}
+	return legacyKey(structure, field, tag, sep)
+}
+
+// legacyKey upper cases the field name as it is and joins it to the
+// structure with sep
+func legacyKey(structure, field string, tag reflect.StructTag, sep string) string {
+	if e, _ := envTag(tag); e != "" {
+		return e
+	}
+	if structure == "" {
+		return strings.ToUpper(field)
+	}
+	return strings.ToUpper(structure + sep + field)
+}
+
+// styledKey joins the words of the field name with wordSep, and the
+// result to the structure with sep
+func styledKey(structure, field string, tag reflect.StructTag,
+	wordSep, sep string, toCase func(string) string) string {
+
+	if e, _ := envTag(tag); e != "" {
+		return e
+	}
+	if field != indexPlaceholder && field != keyPlaceholder {
+		field = strings.Join(splitWords(field), wordSep)
+	}
+	key := field
+	if structure != "" {
+		key = structure + sep + field
+	}
+	// the placeholders of List stay as they are
+	key = toCase(key)
+	for _, placeholder := range []string{indexPlaceholder, keyPlaceholder} {
+		key = strings.ReplaceAll(key, toCase(placeholder), placeholder)
+	}
+	return key
+}
+
+// splitWords splits a name at its underscores and dashes and at the case
+// changes of camel case, keeping an acronym in one piece: HTTPServer is
+// HTTP and Server, UserID is User and ID.
+func splitWords(name string) []string {
+	words := []string{}
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }) {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			if !unicode.IsUpper(runes[i]) {
+				continue
+			}
+			prev := runes[i-1]
+			endOfAcronym := unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || endOfAcronym {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
@@ -0,0 +1,160 @@
+package ecp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	for name, want := range map[string]string{
+		"LogLevel":    "Log Level",
+		"HTTPServer":  "HTTP Server",
+		"UserID":      "User ID",
+		"ID":          "ID",
+		"Version2Max": "Version2 Max",
+		"log_level":   "log level",
+		"redis-host":  "redis host",
+		"lower":       "lower",
+	} {
+		if got := strings.Join(splitWords(name), " "); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestKeyStyle(t *testing.T) {
+	type conf struct {
+		LogLevel   string `default:"info"`
+		HTTPServer struct {
+			ReadTimeout string
+		}
+		Upstreams []struct{ HostName string }
+		DB        map[string]struct{ MaxConns int }
+		Fixed     string `env:"FIXED_KEY"`
+	}
+
+	for _, tc := range []struct {
+		style KeyStyle
+		env   map[string]string
+		list  string
+	}{
+		{
+			LegacyKeys,
+			map[string]string{"KS_LOGLEVEL": "debug", "KS_HTTPSERVER_READTIMEOUT": "1s",
+				"KS_UPSTREAMS_0_HOSTNAME": "a", "KS_DB_MAIN_MAXCONNS": "3"},
+			"KS_LOGLEVEL=info KS_HTTPSERVER_READTIMEOUT= KS_UPSTREAMS_<N>_HOSTNAME= KS_DB_<KEY>_MAXCONNS= FIXED_KEY=",
+		},
+		{
+			SnakeKeys,
+			map[string]string{"KS_LOG_LEVEL": "debug", "KS_HTTP_SERVER_READ_TIMEOUT": "1s",
+				"KS_UPSTREAMS_0_HOST_NAME": "a", "KS_DB_MAIN_MAX_CONNS": "3"},
+			"KS_LOG_LEVEL=info KS_HTTP_SERVER_READ_TIMEOUT= KS_UPSTREAMS_<N>_HOST_NAME= KS_DB_<KEY>_MAX_CONNS= FIXED_KEY=",
+		},
+		{
+			KebabKeys,
+			map[string]string{"ks-log-level": "debug", "ks-http-server-read-timeout": "1s",
+				"ks-upstreams-0-host-name": "a", "ks-db-main-max-conns": "3"},
+			"ks-log-level=info ks-http-server-read-timeout= ks-upstreams-<N>-host-name= ks-db-<KEY>-max-conns= FIXED_KEY=",
+		},
+	} {
+		e := New()
+		e.Advance.KeyStyle = tc.style
+		e.LookupValue = Dotenv(tc.env).Lookup
+		e.Enumerate = Dotenv(tc.env).Enumerate
+
+		c := &conf{}
+		if err := e.Parse(c, "KS"); err != nil {
+			t.Fatal(err)
+		}
+		if c.LogLevel != "debug" || c.HTTPServer.ReadTimeout != "1s" ||
+			len(c.Upstreams) != 1 || c.Upstreams[0].HostName != "a" {
+			t.Errorf("style %d: got %+v", tc.style, c)
+		}
+		for key := range c.DB {
+			if c.DB[key].MaxConns != 3 {
+				t.Errorf("style %d: db %+v", tc.style, c.DB)
+			}
+		}
+		if len(c.DB) != 1 {
+			t.Errorf("style %d: db %+v", tc.style, c.DB)
+		}
+
+		if got := strings.Join(e.List(conf{}, "KS"), " "); got != tc.list {
+			t.Errorf("style %d: list %s", tc.style, got)
+		}
+
+		for key, want := range tc.env {
+			if strings.Contains(key, "LOG") || strings.Contains(key, "log") {
+				if v, err := e.GetString(c, key, "KS"); err != nil || v != want {
+					t.Errorf("style %d: get %s: %v %v", tc.style, key, v, err)
+				}
+			}
+		}
+	}
+}
+
+// a copy of a parser writes the keys of its own style, not the one of
+// the parser it was copied from
+func TestKeyStyleCopy(t *testing.T) {
+	c := *New()
+	c.Advance.KeyStyle = SnakeKeys
+	list := c.List(struct{ LogLevel string }{})
+	if len(list) != 1 || list[0] != "LOG_LEVEL=" {
+		t.Errorf("got %v", list)
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	type conf struct {
+		LogLevel   string
+		HTTPServer struct {
+			ReadTimeout string `default:"1s"`
+		}
+		Upstreams []struct{ HostName string }
+		DB        map[string]struct{ MaxConns int }
+	}
+
+	env := Dotenv{
+		"APP__LOG_LEVEL":                 "debug",
+		"APP__HTTP_SERVER__READ_TIMEOUT": "2s",
+		"APP__UPSTREAMS__0__HOST_NAME":   "a",
+		"APP__DB__MAIN_ONE__MAX_CONNS":   "3",
+		"APP__LOG_LEVLE":                 "typo",
+	}
+	unknown := []string{}
+	e := New()
+	e.Advance.KeyStyle = SnakeKeys
+	e.Advance.Separator = "__"
+	e.Advance.OnUnknown = func(key, suggestion string) { unknown = append(unknown, key+"->"+suggestion) }
+	e.LookupValue = env.Lookup
+	e.Enumerate = env.Enumerate
+
+	c := &conf{}
+	if err := e.Parse(c, "APP"); err != nil {
+		t.Fatal(err)
+	}
+	if c.LogLevel != "debug" || c.HTTPServer.ReadTimeout != "2s" ||
+		len(c.Upstreams) != 1 || c.Upstreams[0].HostName != "a" ||
+		len(c.DB) != 1 || c.DB["MAIN_ONE"].MaxConns != 3 {
+		t.Errorf("got %+v", c)
+	}
+	if strings.Join(unknown, " ") != "APP__LOG_LEVLE->APP__LOG_LEVEL" {
+		t.Errorf("unknown: %v", unknown)
+	}
+
+	if v, err := e.GetString(c, "APP__HTTP_SERVER__READ_TIMEOUT", "APP"); err != nil || v != "2s" {
+		t.Errorf("get: %v %v", v, err)
+	}
+	if list := strings.Join(e.List(conf{}, "APP"), " "); list != "APP__LOG_LEVEL= "+
+		"APP__HTTP_SERVER__READ_TIMEOUT=1s APP__UPSTREAMS__<N>__HOST_NAME= APP__DB__<KEY>__MAX_CONNS=" {
+		t.Errorf("list: %s", list)
+	}
+
+	// the legacy style takes it too
+	e = New()
+	e.Advance.Separator = "."
+	e.LookupValue = Dotenv{"APP.HTTPSERVER.READTIMEOUT": "3s"}.Lookup
+	if err := e.Parse(c, "APP"); err != nil || c.HTTPServer.ReadTimeout != "3s" {
+		t.Errorf("got %+v, %v", c, err)
+	}
+}
@@ -0,0 +1,127 @@
+package ecp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// concrete reports whether a key is a real one, not the template of the
+// keys of a list or a map of sections
+func (f Field) concrete() bool {
+	return !strings.Contains(f.Key, indexPlaceholder) && !strings.Contains(f.Key, keyPlaceholder)
+}
+
+// yamlQuote writes a YAML double quoted scalar, whose escapes are a
+// superset of the ones of Go
+func yamlQuote(s string) string {
+	return strconv.Quote(s)
+}
+
+// ComposeEnvironment writes the environment: mapping of a docker-compose
+// service, every key with its default. A $ is doubled, compose would
+// interpolate it otherwise. A required key without a default is taken
+// from the shell running compose, which fails without it. The keys of
+// lists and maps of sections have no name to be written under, they are
+// left out.
+func (e *ECP) ComposeEnvironment(config interface{}, prefix ...string) string {
+	var b strings.Builder
+	b.WriteString("environment:\n")
+	for _, f := range e.Describe(config, prefix...) {
+		if !f.concrete() {
+			continue
+		}
+		value := yamlQuote(strings.ReplaceAll(f.Default, "$", "$$"))
+		if f.Default == "" && f.Required {
+			value = yamlQuote(fmt.Sprintf("${%s:?%s is required}", f.Key, f.Key))
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", f.Key, value)
+	}
+	return b.String()
+}
+
+// KubernetesManifests writes a ConfigMap named name holding the keys with
+// a default, and the snippet of a container reading it through envFrom.
+// The keys without a default are listed in the env of the snippet with an
+// empty value, to be filled in or pointed to a secret. The keys of lists
+// and maps of sections are left out.
+func (e *ECP) KubernetesManifests(config interface{}, name string, prefix ...string) (configMap, container string) {
+	var data, env strings.Builder
+	for _, f := range e.Describe(config, prefix...) {
+		if !f.concrete() {
+			continue
+		}
+		if f.Default != "" {
+			fmt.Fprintf(&data, "  %s: %s\n", f.Key, yamlQuote(f.Default))
+			continue
+		}
+		comment := ""
+		if f.Required {
+			comment = " # required"
+		}
+		fmt.Fprintf(&env, "  - name: %s\n    value: \"\"%s\n", f.Key, comment)
+	}
+
+	var m strings.Builder
+	m.WriteString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n")
+	fmt.Fprintf(&m, "  name: %s\n", yamlQuote(name))
+	if data.Len() == 0 {
+		m.WriteString("data: {}\n")
+	} else {
+		m.WriteString("data:\n")
+		m.WriteString(data.String())
+	}
+
+	var c strings.Builder
+	c.WriteString("envFrom:\n  - configMapRef:\n")
+	fmt.Fprintf(&c, "      name: %s\n", yamlQuote(name))
+	if env.Len() != 0 {
+		c.WriteString("env:\n")
+		c.WriteString(env.String())
+	}
+	return m.String(), c.String()
+}
+
+// DotenvExample writes a .env.example file: the keys of List, each
+// preceded by its description as a comment. The keys of lists and maps of
+// sections are commented out, their <N> or <KEY> being for the reader to
+// replace. ReadDotenv reads it back.
+func (e *ECP) DotenvExample(config interface{}, prefix ...string) string {
+	var b strings.Builder
+	for i, f := range e.Describe(config, prefix...) {
+		if i != 0 && f.Description != "" {
+			b.WriteString("\n")
+		}
+		for _, line := range strings.Split(f.Description, "\n") {
+			if line != "" {
+				fmt.Fprintf(&b, "# %s\n", line)
+			}
+		}
+		if !f.concrete() {
+			b.WriteString("# ")
+		}
+		fmt.Fprintf(&b, "%s=%s", f.Key, quoteValue(f.Default))
+		if f.Required {
+			b.WriteString(" # required")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ComposeEnvironment writes the environment: mapping of a docker-compose
+// service, see ECP.ComposeEnvironment
+func ComposeEnvironment(config interface{}, prefix ...string) string {
+	return globalEcp.ComposeEnvironment(config, prefix...)
+}
+
+// KubernetesManifests writes a ConfigMap and the snippet of a container
+// reading it, see ECP.KubernetesManifests
+func KubernetesManifests(config interface{}, name string, prefix ...string) (configMap, container string) {
+	return globalEcp.KubernetesManifests(config, name, prefix...)
+}
+
+// DotenvExample writes a .env.example file, see ECP.DotenvExample
+func DotenvExample(config interface{}, prefix ...string) string {
+	return globalEcp.DotenvExample(config, prefix...)
+}
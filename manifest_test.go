@@ -0,0 +1,74 @@
+package ecp
+
+import (
+	"strings"
+	"testing"
+)
+
+type manifestConf struct {
+	Port      int    `default:"8080" desc:"port to listen on"`
+	Token     string `required:"true" desc:"API token,\nkeep it secret"`
+	Greeting  string `default:"hello $USER"`
+	Name      string
+	Upstreams []struct {
+		Host string `default:"localhost"`
+	}
+}
+
+func TestComposeEnvironment(t *testing.T) {
+	want := "environment:\n" +
+		"  APP_PORT: \"8080\"\n" +
+		"  APP_TOKEN: \"${APP_TOKEN:?APP_TOKEN is required}\"\n" +
+		"  APP_GREETING: \"hello $$USER\"\n" +
+		"  APP_NAME: \"\"\n"
+	if got := ComposeEnvironment(manifestConf{}, "APP"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestKubernetesManifests(t *testing.T) {
+	configMap, container := KubernetesManifests(manifestConf{}, "app-env", "APP")
+	wantMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: \"app-env\"\n" +
+		"data:\n" +
+		"  APP_PORT: \"8080\"\n" +
+		"  APP_GREETING: \"hello $USER\"\n"
+	if configMap != wantMap {
+		t.Errorf("got\n%s\nwant\n%s", configMap, wantMap)
+	}
+	wantContainer := "envFrom:\n  - configMapRef:\n      name: \"app-env\"\n" +
+		"env:\n" +
+		"  - name: APP_TOKEN\n    value: \"\" # required\n" +
+		"  - name: APP_NAME\n    value: \"\"\n"
+	if container != wantContainer {
+		t.Errorf("got\n%s\nwant\n%s", container, wantContainer)
+	}
+
+	configMap, container = KubernetesManifests(struct{ A string }{}, "empty")
+	if !strings.HasSuffix(configMap, "data: {}\n") || !strings.HasSuffix(container, "- name: A\n    value: \"\"\n") {
+		t.Errorf("got\n%s\n%s", configMap, container)
+	}
+}
+
+func TestDotenvExample(t *testing.T) {
+	want := "# port to listen on\n" +
+		"APP_PORT=8080\n" +
+		"\n" +
+		"# API token,\n" +
+		"# keep it secret\n" +
+		"APP_TOKEN= # required\n" +
+		"APP_GREETING=\"hello \\$USER\"\n" +
+		"APP_NAME=\n" +
+		"# APP_UPSTREAMS_<N>_HOST=localhost\n"
+	got := DotenvExample(manifestConf{}, "APP")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	env, err := ReadDotenv(strings.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 4 || env["APP_GREETING"] != "hello $USER" || env["APP_TOKEN"] != "" {
+		t.Errorf("read back %v", env)
+	}
+}
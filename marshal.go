@@ -0,0 +1,245 @@
+package ecp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ToEnv writes a config back into KEY=value pairs, the way Parse reads
+// them: the keys are built with BuildKey from the same prefix, and the
+// values written the way the report writes them (slices joined with their
+// separator, durations as 1m30s, text types through MarshalText). A nil
+// pointer field has an empty value, and so do the keys with a default of
+// a nil pointer section, which would otherwise be allocated to hold them.
+//
+// Parsing the pairs back gives the same config. A value that would not
+// come back the same is an error rather than a pair: a slice element
+// holding its separator, or an empty one, without Advance.QuotedSlices,
+// a map key holding one of the map separators or a map value holding
+// the one between entries, a nil element of a list of sections or a nil
+// entry of a map of sections. The key of a map of sections comes back
+// the way BuildKey writes it, upper case by default.
+//
+// The values are not quoted, the pairs are meant for exec.Cmd.Env; see
+// Marshal for a file.
+func (e *ECP) ToEnv(config interface{}, prefix ...string) ([]string, error) {
+	pairs, err := e.configPairs(config, prefix)
+	if err != nil {
+		return nil, err
+	}
+	env := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		env = append(env, pair[0]+"="+pair[1])
+	}
+	return env, nil
+}
+
+// Marshal writes a config as a .env file, the pairs of ToEnv with their
+// values quoted when needed, which ReadDotenv reads back
+func (e *ECP) Marshal(config interface{}, prefix ...string) ([]byte, error) {
+	pairs, err := e.configPairs(config, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, pair := range pairs {
+		fmt.Fprintf(&b, "%s=%s\n", pair[0], quoteValue(pair[1]))
+	}
+	return []byte(b.String()), nil
+}
+
+func (e *ECP) configPairs(config interface{}, prefix []string) ([][2]string, error) {
+	if len(prefix) == 0 {
+		prefix = []string{""}
+	}
+	value := toValue(config)
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a struct or a pointer to a struct, got %T", config)
+	}
+	return e.pairs(value, prefix[0], make(map[reflect.Type]bool, 1))
+}
+
+// pairs walks a config the way keys does and returns the key and the
+// value of every field, sections included
+func (e *ECP) pairs(configValue reflect.Value, parentName string,
+	visiting map[reflect.Type]bool) ([][2]string, error) {
+
+	pairs := [][2]string{}
+	configType := configValue.Type()
+	if visiting[configType] {
+		return pairs, nil
+	}
+	visiting[configType] = true
+	defer delete(visiting, configType)
+
+	for index := 0; index < configValue.NumField(); index++ {
+		if !configType.Field(index).IsExported() {
+			continue
+		}
+		all := e.getAll(getAllOpt{configType, configValue, index, parentName})
+		if all.key == "" {
+			continue
+		}
+		field := all.value
+		// the sections to walk, with the prefix of their keys
+		var sections []reflect.Value
+		var prefixes []string
+
+		switch {
+		case field.Kind() == reflect.Struct && isSection(field):
+			sections = append(sections, field)
+			prefixes = append(prefixes, e.key(parentName, all.parent, all.tag))
+
+		case isSection(field):
+			prefix := e.key(parentName, all.parent, all.tag)
+			switch {
+			case !field.IsNil():
+				sections = append(sections, field.Elem())
+				prefixes = append(prefixes, prefix)
+			case !visiting[field.Type().Elem()]:
+				pairs = append(pairs, e.nilPairs(field.Type().Elem(), prefix)...)
+			}
+
+		case isSectionList(field.Type()):
+			prefix := e.key(parentName, all.parent, all.tag)
+			for i := 0; i < field.Len(); i++ {
+				elem := field.Index(i)
+				if elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						return nil, nilSection(e.indexKey(prefix, i), elem)
+					}
+					elem = elem.Elem()
+				}
+				sections = append(sections, elem)
+				prefixes = append(prefixes, e.indexKey(prefix, i))
+			}
+
+		case isSectionMap(field.Type()):
+			prefix := e.key(parentName, all.parent, all.tag)
+			// in the order of the keys, the one of a map is random
+			entries := map[string]reflect.Value{}
+			names := []string{}
+			iter := field.MapRange()
+			for iter.Next() {
+				name := e.formatValue("", iter.Key())
+				entry := iter.Value()
+				if entry.Kind() == reflect.Ptr {
+					if entry.IsNil() {
+						return nil, nilSection(e.key(prefix, name, ""), entry)
+					}
+					entry = entry.Elem()
+				}
+				entries[name] = entry
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				sections = append(sections, entries[name])
+				prefixes = append(prefixes, e.key(prefix, name, ""))
+			}
+
+		case !canSetType(field.Type()) && !unmarshalsText(field.Type()):
+			continue
+
+		default:
+			v := e.formatValue(all.tag, field)
+			if err := e.writable(all.tag, field, v); err != nil {
+				return nil, &ParseError{Key: all.key, Type: field.Type(), Value: v, Err: err}
+			}
+			pairs = append(pairs, [2]string{all.key, v})
+		}
+
+		for i, section := range sections {
+			sectionPairs, err := e.pairs(section, prefixes[i], visiting)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, sectionPairs...)
+		}
+	}
+	return pairs, nil
+}
+
+// nilSection is the error of a nil element of a list or a map of
+// sections, which has no keys to be written with: Parse would not give it
+// back
+func nilSection(prefix string, elem reflect.Value) error {
+	return &ParseError{
+		Key:  prefix,
+		Type: elem.Type(),
+		Err:  errors.New("a nil section cannot be written"),
+	}
+}
+
+// nilPairs are the keys with a default of a nil section, with an empty
+// value: Parse takes a key set to nothing as a value it has no use for,
+// where a missing one would get its default and allocate the section
+func (e *ECP) nilPairs(typ reflect.Type, prefix string) [][2]string {
+	pairs := [][2]string{}
+	for _, f := range e.keys(reflect.New(typ).Interface(), prefix) {
+		// a list or a map of sections gets no element from a default
+		if f.Default == "" || strings.Contains(f.Key, indexPlaceholder) ||
+			strings.Contains(f.Key, keyPlaceholder) {
+			continue
+		}
+		pairs = append(pairs, [2]string{f.Key, ""})
+	}
+	return pairs
+}
+
+// writable returns an error when v, the value formatValue wrote for a
+// field, would not parse back into the same value
+func (e *ECP) writable(tag reflect.StructTag, field reflect.Value, v string) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	if unmarshalsText(field.Type()) {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Slice:
+		if e.Advance.QuotedSlices || field.Len() == 0 {
+			return nil
+		}
+		if v == "" {
+			return errors.New("an empty element would not be read back, see QuotedSlices")
+		}
+		parts := split(v, e.splitChar(tag))
+		for i := 0; i < field.Len(); i++ {
+			elem := e.formatValue("", field.Index(i))
+			if i >= len(parts) || parts[i] != elem {
+				return fmt.Errorf("element %q would not split back, see QuotedSlices", elem)
+			}
+		}
+
+	case reflect.Map:
+		entrySep, pairSep := e.mapSeparators()
+		iter := field.MapRange()
+		for iter.Next() {
+			k := e.formatValue("", iter.Key())
+			val := e.formatValue("", iter.Value())
+			if strings.Contains(k, entrySep) || strings.Contains(k, pairSep) ||
+				strings.Contains(val, entrySep) {
+				return fmt.Errorf("entry %q holds a separator of the map", k+pairSep+val)
+			}
+		}
+	}
+	return nil
+}
+
+// ToEnv writes a config back into KEY=value pairs, see ECP.ToEnv
+func ToEnv(config interface{}, prefix ...string) ([]string, error) {
+	return globalEcp.ToEnv(config, prefix...)
+}
+
+// Marshal writes a config as a .env file, see ECP.Marshal
+func Marshal(config interface{}, prefix ...string) ([]byte, error) {
+	return globalEcp.Marshal(config, prefix...)
+}
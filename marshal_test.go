@@ -0,0 +1,165 @@
+package ecp
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type marshalConf struct {
+	Name    string `default:"app"`
+	Empty   string `default:"not empty"`
+	Port    int
+	Ratio   float64
+	Debug   bool
+	Timeout time.Duration
+	Retry   *int
+	Unset   *int `default:"3"`
+	IP      net.IP
+	Hosts   []string
+	Tags    []string          `sep:","`
+	Labels  map[string]string `yaml:"labels"`
+	Redis   struct {
+		Host string
+		DB   int `env:"MARSHAL_REDIS_DB"`
+	}
+	TLS       *struct{ Cert string } `yaml:"tls"`
+	Cache     *struct{ Size int }    `yaml:"cache"`
+	Pool      *marshalPool           `yaml:"pool"`
+	Upstreams []struct{ Host string }
+	Shards    map[string]*struct{ Port int }
+	Ignored   string `yaml:"-"`
+}
+
+// the defaults of a nil section would allocate it
+type marshalPool struct {
+	Size int `default:"4"`
+}
+
+func TestToEnv(t *testing.T) {
+	retry := 5
+	in := marshalConf{
+		Name:    "my app",
+		Port:    8080,
+		Ratio:   0.5,
+		Debug:   true,
+		Timeout: 90 * time.Second,
+		Retry:   &retry,
+		IP:      net.ParseIP("10.0.0.1"),
+		Hosts:   []string{"a", "b"},
+		Tags:    []string{"x y", "z"},
+		Labels:  map[string]string{"team": "core", "env": "prod"},
+		Ignored: "secret",
+	}
+	in.Redis.Host = "redis.local"
+	in.Redis.DB = 2
+	in.Cache = &struct{ Size int }{64}
+	in.Upstreams = []struct{ Host string }{{"u0"}, {"u1"}}
+	in.Shards = map[string]*struct{ Port int }{"B": {2}, "A": {1}}
+
+	env, err := ToEnv(in, "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"M_NAME=my app", "M_EMPTY=", "M_PORT=8080", "M_RATIO=0.5", "M_DEBUG=true",
+		"M_TIMEOUT=1m30s", "M_RETRY=5", "M_UNSET=", "M_IP=10.0.0.1", "M_HOSTS=a b",
+		"M_TAGS=x y,z", "M_LABELS=env=prod,team=core", "M_REDIS_HOST=redis.local",
+		"MARSHAL_REDIS_DB=2", "M_CACHE_SIZE=64", "M_POOL_SIZE=", "M_UPSTREAMS_0_HOST=u0",
+		"M_UPSTREAMS_1_HOST=u1", "M_SHARDS_A_PORT=1", "M_SHARDS_B_PORT=2",
+	}
+	if strings.Join(env, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s", strings.Join(env, "\n"))
+	}
+
+	// and back
+	in.Ignored = ""
+	source := Dotenv{}
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		source[k] = v
+	}
+	e := New()
+	e.LookupValue = source.Lookup
+	e.Enumerate = source.Enumerate
+	out := marshalConf{}
+	if err := e.Parse(&out, "M"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip:\n%+v\n%+v", in, out)
+	}
+
+	t.Run("marshal", func(t *testing.T) {
+		content, err := Marshal(&in, "M")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(content, []byte("M_NAME=\"my app\"\n")) {
+			t.Errorf("got %s", content)
+		}
+		file, err := ReadDotenv(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.LookupValue = file.Lookup
+		e.Enumerate = file.Enumerate
+		out := marshalConf{}
+		if err := e.Parse(&out, "M"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("round trip:\n%+v\n%+v", in, out)
+		}
+	})
+
+	// values Parse would read back as something else
+	t.Run("unwritable values", func(t *testing.T) {
+		for name, config := range map[string]interface{}{
+			"Z_TAGS": struct{ Tags []string }{[]string{"a b", "c"}},
+			"Z_EMPTY": struct {
+				Empty []string `sep:","`
+			}{[]string{""}},
+			"Z_LABELS": struct{ Labels map[string]string }{map[string]string{"k": "v,w=z"}},
+		} {
+			_, err := ToEnv(config, "Z")
+			var perr *ParseError
+			if !errors.As(err, &perr) || perr.Key != name {
+				t.Errorf("%s: got %v", name, err)
+			}
+		}
+
+		e := New()
+		e.Advance.QuotedSlices = true
+		env, err := e.ToEnv(struct{ Tags []string }{[]string{"a b", "c"}}, "Z")
+		if err != nil || env[0] != `Z_TAGS="a b" c` {
+			t.Errorf("got %q, %v", env, err)
+		}
+	})
+
+	// a nil section has no keys, Parse would drop it or stop at it
+	t.Run("nil sections", func(t *testing.T) {
+		type server struct{ Host string }
+		for name, config := range map[string]interface{}{
+			"Z_UP_0": struct{ Up []*server }{[]*server{nil, {"b"}}},
+			"Z_DB_A": struct{ DB map[string]*server }{map[string]*server{"A": nil}},
+		} {
+			_, err := ToEnv(config, "Z")
+			var perr *ParseError
+			if !errors.As(err, &perr) || perr.Key != name {
+				t.Errorf("%s: got %v", name, err)
+			}
+			if _, err := Marshal(config, "Z"); err == nil {
+				t.Errorf("%s: Marshal should fail too", name)
+			}
+		}
+	})
+
+	if _, err := ToEnv("not a struct"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,83 @@
+package ecp
+
+import (
+	"sort"
+	"strings"
+)
+
+// foldKey is the form two keys are compared in under Advance.IgnoreCase
+// and Advance.NormalizeKeys
+func (e *ECP) foldKey(key string) string {
+	if e.Advance.IgnoreCase {
+		key = strings.ToUpper(key)
+	}
+	if e.Advance.NormalizeKeys {
+		key = strings.NewReplacer("-", "_", ".", "_").Replace(key)
+	}
+	return key
+}
+
+// folded returns a copy of the parser whose lookups find a key written in
+// another case or with other separators, see Advance.IgnoreCase. Each
+// source is enumerated once into an index of its keys by their folded
+// form, a source that cannot enumerate is only looked up as is.
+func (e *ECP) folded() *ECP {
+	folded := *e
+	if len(e.Sources) == 0 {
+		folded.LookupValue, folded.Enumerate = e.foldSource(e.LookupValue, e.Enumerate)
+		return &folded
+	}
+	folded.Sources = make([]Source, len(e.Sources))
+	for i, s := range e.Sources {
+		s.Lookup, s.Enumerate = e.foldSource(s.Lookup, s.Enumerate)
+		folded.Sources[i] = s
+	}
+	return &folded
+}
+
+func (e *ECP) foldSource(lookup LookupValueFunc, enumerate EnumerateFunc) (LookupValueFunc, EnumerateFunc) {
+	if lookup == nil || enumerate == nil {
+		return lookup, enumerate
+	}
+
+	// the first of the keys folding the same way wins, in sorted order
+	// for the choice to be the same from one run to the next
+	names := enumerate("")
+	sort.Strings(names)
+	index := make(map[string]string, len(names))
+	for _, name := range names {
+		if _, ok := index[e.foldKey(name)]; !ok {
+			index[e.foldKey(name)] = name
+		}
+	}
+
+	foldedLookup := func(key string) (string, bool) {
+		if v, ok := lookup(key); ok {
+			return v, true
+		}
+		if name, ok := index[e.foldKey(key)]; ok {
+			return lookup(name)
+		}
+		return "", false
+	}
+	// the keys are enumerated the way the prefix is written, for the
+	// caller to find its own keys among them
+	foldedEnumerate := func(prefix string) []string {
+		foldedPrefix := e.foldKey(prefix)
+		lower := prefix == strings.ToLower(prefix) && prefix != strings.ToUpper(prefix)
+		keys := []string{}
+		for folded := range index {
+			if !strings.HasPrefix(folded, foldedPrefix) {
+				continue
+			}
+			rest := folded[len(foldedPrefix):]
+			if lower && e.Advance.IgnoreCase {
+				rest = strings.ToLower(rest)
+			}
+			keys = append(keys, prefix+rest)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	return foldedLookup, foldedEnumerate
+}
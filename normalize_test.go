@@ -0,0 +1,87 @@
+package ecp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreCase(t *testing.T) {
+	type conf struct {
+		Port  int
+		Redis struct{ Host string }
+		DB    map[string]struct{ Host string }
+	}
+	file := Dotenv{
+		"app_port":         "8080",
+		"App.Redis-Host":   "redis.local",
+		"app_db_main_host": "db.local",
+		"app_unknown":      "x",
+	}
+
+	for _, tc := range []struct {
+		ignoreCase, normalize bool
+		want                  string
+	}{
+		{false, false, "0  0"},
+		{true, false, "8080  1"},
+		{true, true, "8080 redis.local 1"},
+	} {
+		unknown := []string{}
+		e := New()
+		e.Advance.IgnoreCase = tc.ignoreCase
+		e.Advance.NormalizeKeys = tc.normalize
+		e.Advance.OnUnknown = func(key, _ string) { unknown = append(unknown, key) }
+		e.Sources = []Source{file.Source("file")}
+
+		c := &conf{}
+		if err := e.Parse(c, "APP"); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join([]string{strconv.Itoa(c.Port), c.Redis.Host, strconv.Itoa(len(c.DB))}, " ")
+		if got != tc.want {
+			t.Errorf("ignore case %v, normalize %v: got %q, want %q",
+				tc.ignoreCase, tc.normalize, got, tc.want)
+		}
+		if len(c.DB) == 1 && c.DB["MAIN"].Host != "db.local" {
+			t.Errorf("db: %+v", c.DB)
+		}
+		if tc.normalize && strings.Join(unknown, " ") != "APP_UNKNOWN" {
+			t.Errorf("unknown: %v", unknown)
+		}
+	}
+
+	// the exact key still comes first
+	e := New()
+	e.Advance.IgnoreCase = true
+	e.LookupValue = Dotenv{"APP_PORT": "1", "app_port": "2"}.Lookup
+	e.Enumerate = Dotenv{"APP_PORT": "1", "app_port": "2"}.Enumerate
+	c := &conf{}
+	if err := e.Parse(c, "APP"); err != nil || c.Port != 1 {
+		t.Errorf("got %+v, %v", c, err)
+	}
+}
+
+// without Sources, the keys are folded through Enumerate, which has to
+// come with LookupValue
+func TestIgnoreCaseDotenv(t *testing.T) {
+	env, err := ReadDotenv(strings.NewReader("app_port=8080\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type conf struct{ Port int }
+
+	e := New()
+	e.Advance.IgnoreCase = true
+	e.LookupValue = env.Lookup
+	c := &conf{}
+	if err := e.Parse(c, "APP"); err != nil || c.Port != 0 {
+		t.Errorf("the environment has no app_port, got %+v, %v", c, err)
+	}
+
+	e.Enumerate = env.Enumerate
+	c = &conf{}
+	if err := e.Parse(c, "APP"); err != nil || c.Port != 8080 {
+		t.Errorf("got %+v, %v", c, err)
+	}
+}
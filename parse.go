@@ -1,25 +1,48 @@
 package ecp
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"unicode/utf8"
 )
 
+// splitChar is the separator of slice elements, the one of the sep tag
+// of the field or else Advance.SplitChar
+func (e *ECP) splitChar(tag reflect.StructTag) string {
+	if sep := tag.Get("sep"); sep != "" {
+		return sep
+	}
+	if e.Advance.SplitChar == "" {
+		// an empty separator would make strings.Split cut between every
+		// rune, which is never what the caller meant
+		return space
+	}
+	return e.Advance.SplitChar
+}
+
+// mapSeparators are the separators between the entries of a map value,
+// and between the key and the value of an entry
+func (e *ECP) mapSeparators() (entry, pair string) {
+	entry, pair = e.Advance.MapSplitChar, e.Advance.MapPairChar
+	if entry == "" {
+		entry = comma
+	}
+	if pair == "" {
+		pair = equal
+	}
+	return entry, pair
+}
+
 // split cuts a value into slice elements.
 //
-// Only the default separator collapses repeats, so "a  b" yields two
+// Only a space separator collapses repeats, so "a  b" yields two
 // elements instead of three (one of them empty and unparsable). Any
 // separator the caller chose is taken literally, including a tab or a
 // newline, which a "is it whitespace" test used to swallow.
-func (e *ECP) split(v string) []string {
-	sep := e.Advance.SplitChar
-	if sep == "" {
-		// an empty separator would make strings.Split cut between every
-		// rune, which is never what the caller meant
-		sep = space
-	}
-
+func split(v, sep string) []string {
 	parts := strings.Split(v, sep)
 	if sep != space {
 		return parts
@@ -34,10 +57,84 @@ func (e *ECP) split(v string) []string {
 	return collapsed
 }
 
-// parseSlice supports slices of string, bool, int, int8, int16, int32,
-// int64, uint, uint8, uint16, uint32, uint64, float32, float64 and
-// time.Duration, including named types built on top of them.
-func (e *ECP) parseSlice(v string, field reflect.Value) error {
+// splitQuoted cuts a value at the separators outside of double quotes, a
+// backslash escaping the next character anywhere: with a space separator
+// `"a b" c\ d ""` is "a b", "c d" and an empty element. As with split, a
+// space separator collapses repeats, but a quoted empty element stays.
+func splitQuoted(v, sep string) ([]string, error) {
+	parts := []string{}
+	var b strings.Builder
+	inQuotes, quoted := false, false
+	flush := func() {
+		if b.Len() != 0 || quoted || sep != space {
+			parts = append(parts, b.String())
+		}
+		b.Reset()
+		quoted = false
+	}
+
+	for i := 0; i < len(v); {
+		switch {
+		case v[i] == '\\':
+			if i+1 == len(v) {
+				return nil, errors.New("trailing backslash")
+			}
+			r, size := utf8.DecodeRuneInString(v[i+1:])
+			b.WriteRune(r)
+			i += 1 + size
+		case v[i] == '"':
+			inQuotes, quoted = !inQuotes, true
+			i++
+		case !inQuotes && strings.HasPrefix(v[i:], sep):
+			flush()
+			i += len(sep)
+		default:
+			b.WriteByte(v[i])
+			i++
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", v)
+	}
+	flush()
+	return parts, nil
+}
+
+// quoteElement is the reverse of splitQuoted for a single element
+func quoteElement(s, sep string) string {
+	if s != "" && !strings.ContainsAny(s, `"\`) && !strings.Contains(s, sep) {
+		return s
+	}
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+	return `"` + s + `"`
+}
+
+// jsonElements reads a JSON array, its strings unquoted and any other
+// element (a number, a boolean) left as written for setValue to convert
+func jsonElements(v string) ([]string, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(v), &raw); err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(raw))
+	for i, element := range raw {
+		if len(element) != 0 && element[0] == '"' {
+			if err := json.Unmarshal(element, &parts[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		parts[i] = string(element)
+	}
+	return parts, nil
+}
+
+// parseSlice splits a value with the separator of the field, see
+// splitChar, understanding quotes with Advance.QuotedSlices and a JSON
+// array with Advance.JSONSlices. It supports slices of string, bool, int,
+// int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32,
+// float64 and time.Duration, including named types built on top of them.
+func (e *ECP) parseSlice(tag reflect.StructTag, v string, field reflect.Value) error {
 	if v == "" {
 		return nil
 	}
@@ -51,7 +148,23 @@ func (e *ECP) parseSlice(v string, field reflect.Value) error {
 
 	// either space nor commas is perfect, but I think space is better
 	// since it's more natural: fmt.Println([]int{1, 2, 3}) = [1 2 3]
-	parts := e.split(v)
+	var parts []string
+	switch sep := e.splitChar(tag); {
+	case e.Advance.JSONSlices && strings.HasPrefix(strings.TrimSpace(v), "["):
+		elements, err := jsonElements(v)
+		if err != nil {
+			return fmt.Errorf("bad JSON array: %w", err)
+		}
+		parts = elements
+	case e.Advance.QuotedSlices:
+		elements, err := splitQuoted(v, sep)
+		if err != nil {
+			return err
+		}
+		parts = elements
+	default:
+		parts = split(v, sep)
+	}
 
 	// build the slice through the field's own type so that a named
 	// element type ([]Level) stays assignable
@@ -66,29 +179,85 @@ func (e *ECP) parseSlice(v string, field reflect.Value) error {
 	return nil
 }
 
-// setPointer fills a pointer field, allocating the pointed-to value.
+// parseMap fills a map from "key=value" entries, "a=1,b=2" with the
+// default separators. Keys and values go through setValue, so a
+// map[Level]time.Duration works just like a []Level does.
 //
-// The value is built with reflect.New from the field's own element type,
-// which keeps named pointer types (*time.Duration, *Level, ...)
-// assignable and lets *time.Duration accept the same "10s" syntax as
-// time.Duration.
-func (e *ECP) setPointer(field reflect.Value, v string) error {
-	elemType := field.Type().Elem()
-	pointer := reflect.New(elemType)
+// The map is built from scratch: an environment value describes the
+// whole map, it is not merged into the one the caller set.
+func (e *ECP) parseMap(v string, field reflect.Value) error {
+	if v == "" {
+		return nil
+	}
 
-	if elemType.Kind() == reflect.Slice {
-		// parseSlice needs the slice value itself, not the pointer to it
-		if err := e.parseSlice(v, pointer.Elem()); err != nil {
-			return err
+	if !field.CanSet() {
+		return fmt.Errorf("field is not addressable")
+	}
+	if field.Kind() != reflect.Map {
+		return fmt.Errorf("field is not map")
+	}
+
+	entrySep, pairSep := e.mapSeparators()
+
+	typ := field.Type()
+	m := reflect.MakeMap(typ)
+	for _, entry := range strings.Split(v, entrySep) {
+		// "a=1,,b=2" or a trailing separator is not worth an error
+		if entry == "" {
+			continue
 		}
-	} else {
-		v, err := expandNumber(elemType, v)
-		if err != nil {
+		k, val, found := strings.Cut(entry, pairSep)
+		if !found {
+			return fmt.Errorf("bad map entry %q, want key%svalue", entry, pairSep)
+		}
+
+		key := reflect.New(typ.Key()).Elem()
+		if err := setValue(key, k); err != nil {
 			return err
 		}
-		if err := setValue(pointer.Elem(), v); err != nil {
+		value := reflect.New(typ.Elem()).Elem()
+		if err := setValue(value, val); err != nil {
 			return err
 		}
+		m.SetMapIndex(key, value)
+	}
+	field.Set(m)
+
+	return nil
+}
+
+// setField converts a value into a field that is not a section, be it a
+// scalar, a slice, a map or a pointer to one of those
+func (e *ECP) setField(tag reflect.StructTag, field reflect.Value, v string) error {
+	// a TextUnmarshaler (net.IP) is a single value, not a collection
+	textual := unmarshalsText(field.Type())
+
+	switch kind := field.Kind(); {
+	case kind == reflect.Ptr:
+		return e.setPointer(tag, field, v)
+	case kind == reflect.Slice && !textual:
+		return e.parseSlice(tag, v, field)
+	case kind == reflect.Map && !textual:
+		return e.parseMap(v, field)
+	}
+
+	v, err := expandNumber(field.Type(), v)
+	if err != nil {
+		return err
+	}
+	return setValue(field, v)
+}
+
+// setPointer fills a pointer field, allocating the pointed-to value.
+//
+// The value is built with reflect.New from the field's own element type,
+// which keeps named pointer types (*time.Duration, *Level, ...)
+// assignable and lets *time.Duration accept the same "10s" syntax as
+// time.Duration.
+func (e *ECP) setPointer(tag reflect.StructTag, field reflect.Value, v string) error {
+	pointer := reflect.New(field.Type().Elem())
+	if err := e.setField(tag, pointer.Elem(), v); err != nil {
+		return err
 	}
 
 	field.Set(pointer)
@@ -1,7 +1,9 @@
 package ecp
 
 import (
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -33,7 +35,9 @@ func TestParseSlice(t *testing.T) {
 		NotSlice    string
 	}
 
-	var parseSlice = globalEcp.parseSlice
+	parseSlice := func(v string, field reflect.Value) error {
+		return globalEcp.parseSlice("", v, field)
+	}
 
 	s := &slices{}
 
@@ -179,3 +183,189 @@ func TestParseSlice(t *testing.T) {
 		}
 	})
 }
+
+func TestParseMap(t *testing.T) {
+	type conf struct {
+		Labels  map[string]string        `default:"a=1,b=2"`
+		Weights map[string]int           `env:"MAP_WEIGHTS"`
+		Levels  map[level]time.Duration  `env:"MAP_LEVELS"`
+		Pointer *map[string]float64      `env:"MAP_POINTER"`
+		Bad     map[string]int           `env:"MAP_BAD"`
+		Keep    map[string]string        `default:"x=y"`
+		Chans   map[string]chan struct{} `env:"MAP_CHANS"`
+		Ptrs    map[string]*int          `env:"MAP_PTRS"`
+		Lists   map[string][]string      `env:"MAP_LISTS"`
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		withEnv(t, "MAP_WEIGHTS", "x=10,y=20,")
+		withEnv(t, "MAP_LEVELS", "1=1s,2=1d")
+		withEnv(t, "MAP_POINTER", "pi=3.14")
+		c := &conf{Keep: map[string]string{"k": "v"}}
+		if err := Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Labels) != 2 || c.Labels["b"] != "2" {
+			t.Errorf("labels: %v", c.Labels)
+		}
+		// a trailing separator is ignored
+		if len(c.Weights) != 2 || c.Weights["y"] != 20 {
+			t.Errorf("weights: %v", c.Weights)
+		}
+		if c.Levels[2] != 24*time.Hour {
+			t.Errorf("levels: %v", c.Levels)
+		}
+		if c.Pointer == nil || (*c.Pointer)["pi"] != 3.14 {
+			t.Errorf("pointer: %v", c.Pointer)
+		}
+		if len(c.Keep) != 1 || c.Keep["k"] != "v" {
+			t.Errorf("a default must not overwrite the caller's map: %v", c.Keep)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for env, value := range map[string]string{
+			"MAP_BAD":   "a",
+			"MAP_CHANS": "a=1",
+			"MAP_PTRS":  "a=1",
+			"MAP_LISTS": "a=1",
+		} {
+			os.Setenv(env, value)
+			err := Parse(&conf{})
+			os.Unsetenv(env)
+			if err == nil {
+				t.Errorf("%s=%s should not parse", env, value)
+			}
+		}
+	})
+
+	t.Run("custom separators", func(t *testing.T) {
+		e := New()
+		e.Advance.MapSplitChar = ";"
+		e.Advance.MapPairChar = ":"
+		c := &struct {
+			M map[string]string `default:"a:1;b:x=y"`
+		}{}
+		if err := e.Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if c.M["b"] != "x=y" {
+			t.Errorf("custom separators: %v", c.M)
+		}
+	})
+
+	t.Run("listed in the same syntax", func(t *testing.T) {
+		list := strings.Join(List(conf{}), " ")
+		for _, want := range []string{"LABELS=a=1,b=2", "MAP_WEIGHTS="} {
+			if !strings.Contains(list, want) {
+				t.Errorf("missing %s in %s", want, list)
+			}
+		}
+		// maps Parse cannot fill are not listed either
+		for _, unwanted := range []string{"MAP_CHANS", "MAP_PTRS", "MAP_LISTS"} {
+			if strings.Contains(list, unwanted) {
+				t.Errorf("%s should not be listed: %s", unwanted, list)
+			}
+		}
+	})
+}
+
+func TestSepTag(t *testing.T) {
+	type conf struct {
+		Hosts []string `default:"a b  c"`
+		Tags  []string `sep:"," default:"x,y z"`
+		Paths []string `sep:";"`
+		Ports *[]int   `sep:","`
+	}
+
+	withEnv(t, "PATHS", "/bin;/usr/local/bin")
+	withEnv(t, "PORTS", "80,443")
+
+	c := &conf{}
+	report, err := ParseWithReport(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.Hosts, "|") != "a|b|c" || strings.Join(c.Tags, "|") != "x|y z" ||
+		strings.Join(c.Paths, "|") != "/bin|/usr/local/bin" ||
+		c.Ports == nil || len(*c.Ports) != 2 || (*c.Ports)[1] != 443 {
+		t.Errorf("got %+v", c)
+	}
+
+	// the report writes the values back with the same separators
+	values := map[string]string{}
+	for _, r := range report {
+		values[r.Key] = r.Value
+	}
+	if values["TAGS"] != "x,y z" || values["PATHS"] != "/bin;/usr/local/bin" || values["PORTS"] != "80,443" {
+		t.Errorf("report: %v", values)
+	}
+
+	if list := strings.Join(List(conf{}), " "); !strings.Contains(list, `TAGS="x,y z"`) {
+		t.Errorf("list: %s", list)
+	}
+}
+
+func TestQuotedSlices(t *testing.T) {
+	for v, want := range map[string]string{
+		`a b  c`:          "a|b|c",
+		`"a b" c\ d`:      "a b|c d",
+		`"" x`:            "|x",
+		`"say \"hi\"" \\`: `say "hi"|\`,
+		`h"al"f`:          "half",
+		`é"\é"`:           "éé",
+	} {
+		got, err := splitQuoted(v, " ")
+		if err != nil || strings.Join(got, "|") != want {
+			t.Errorf("%s: got %q, %v", v, got, err)
+		}
+	}
+	if got, err := splitQuoted(`a,"b,c",,d`, ","); err != nil || strings.Join(got, "|") != "a|b,c||d" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, v := range []string{`"a b`, `a\`} {
+		if _, err := splitQuoted(v, " "); err == nil {
+			t.Errorf("%s: expected an error", v)
+		}
+	}
+
+	type conf struct {
+		Headers []string `default:"\"X-A: 1\" \"X-B: 2\""`
+		Ports   []int
+		Delays  []time.Duration `sep:","`
+	}
+	withEnv(t, "PORTS", `[80, 443]`)
+	withEnv(t, "DELAYS", `["1s","2m"]`)
+
+	e := New()
+	e.Advance.QuotedSlices = true
+	e.Advance.JSONSlices = true
+	c := &conf{}
+	report, err := e.ParseWithReport(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.Headers, "|") != "X-A: 1|X-B: 2" || len(c.Ports) != 2 || c.Ports[1] != 443 ||
+		len(c.Delays) != 2 || c.Delays[1] != 2*time.Minute {
+		t.Errorf("got %+v", c)
+	}
+	for _, r := range report {
+		if r.Key == "HEADERS" && r.Value != `"X-A: 1" "X-B: 2"` {
+			t.Errorf("report: %s", r.Value)
+		}
+	}
+
+	withEnv(t, "PORTS", `[80,`)
+	if err := e.Parse(&conf{}); err == nil || !strings.Contains(err.Error(), "bad JSON array") {
+		t.Errorf("got %v", err)
+	}
+
+	// without JSONSlices, a [ is just a character
+	withEnv(t, "PORTS", "")
+	withEnv(t, "DELAYS", "")
+	withEnv(t, "HEADERS", `[a] b`)
+	e.Advance.JSONSlices = false
+	if err := e.Parse(c); err != nil || strings.Join(c.Headers, "|") != "[a]|b" {
+		t.Errorf("got %+v, %v", c, err)
+	}
+}
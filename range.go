@@ -3,14 +3,32 @@ package ecp
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// the expansion of parseScientific is only ever fed to ParseInt/ParseUint,
-// so anything beyond 20 digits is out of range for every Go integer type
-const maxExponent = 20
+const (
+	// the expansion of parseScientific is only ever fed to
+	// ParseInt/ParseUint, so anything beyond 20 digits is out of range for
+	// every Go integer type
+	maxExponent = 20
+
+	// a list of sections stops growing there, even if a LookupValue
+	// answering yes to every key would have it go on forever
+	maxListLength = 1000
+
+	// stands for the index of a list of sections in a listed key,
+	// UPSTREAMS_<N>_HOST
+	indexPlaceholder = "<N>"
+	// stands for the key of a map of sections in a listed key,
+	// DB_<KEY>_HOST
+	keyPlaceholder = "<KEY>"
+	// marks the segment of a key that varies from an entry of a map of
+	// sections to the next, no BuildKey changes it and no key has it
+	segmentMarker = "\x00"
+)
 
 func toValue(config interface{}) reflect.Value {
 	value, ok := config.(reflect.Value)
@@ -26,17 +44,50 @@ func toValue(config interface{}) reflect.Value {
 
 // isSection reports whether a field is a nested config section, that is a
 // struct or a pointer to a struct. Sections are walked into even when no
-// value of their own is available.
+// value of their own is available. A struct implementing
+// encoding.TextUnmarshaler (big.Int, time.Time, ...) is a single value.
 func isSection(field reflect.Value) bool {
 	switch field.Kind() {
 	case reflect.Struct:
-		return true
+		return !unmarshalsText(field.Type())
 	case reflect.Ptr:
-		return field.Type().Elem().Kind() == reflect.Struct
+		elem := field.Type().Elem()
+		return elem.Kind() == reflect.Struct && !unmarshalsText(elem)
 	}
 	return false
 }
 
+// isSectionList reports whether a field is a list of sections, []Server
+// or []*Server. Element i is the section keyed UPSTREAMS_i.
+func isSectionList(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice || unmarshalsText(typ) {
+		return false
+	}
+	elem := sectionType(typ)
+	return elem.Kind() == reflect.Struct && !unmarshalsText(elem)
+}
+
+// isSectionMap reports whether a field is a map of sections,
+// map[string]DB or map[string]*DB. The entry "primary" is the section
+// keyed DB_PRIMARY.
+func isSectionMap(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Map || unmarshalsText(typ) {
+		return false
+	}
+	elem := sectionType(typ)
+	return elem.Kind() == reflect.Struct && !unmarshalsText(elem)
+}
+
+// sectionType is the struct type of the elements of a list or a map of
+// sections
+func sectionType(typ reflect.Type) reflect.Type {
+	elem := typ.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem
+}
+
 type getAllOpt struct {
 	typ    reflect.Type
 	value  reflect.Value
@@ -50,6 +101,9 @@ type getAllResult struct {
 	parent string // struct name
 	key    string // key name, empty means "ignore this field"
 	defVal string // default value
+
+	aliases  []string // other names of the key, tried in order after it
+	required bool     // the key must have a value
 }
 
 func (e *ECP) getAll(opts getAllOpt) getAllResult {
@@ -75,11 +129,13 @@ func (e *ECP) getAll(opts getAllOpt) getAllResult {
 
 	// a "-" tag means "ignore this field", the same way encoding/json
 	// reads it; leaving the key empty makes both Parse and List skip it
-	if r.parent == "-" || field.Tag.Get("env") == "-" {
+	if env, _ := envTag(r.tag); r.parent == "-" || env == "-" {
 		return r
 	}
 
-	r.key = e.BuildKey(opts.parent, r.parent, r.tag)
+	r.key = e.key(opts.parent, r.parent, r.tag)
+	r.required = isRequired(r.tag)
+	r.aliases = envAliases(r.tag)
 
 	return r
 }
@@ -89,7 +145,10 @@ type roOption struct {
 	target interface{}
 	setDef bool   // set default value
 	prefix string // prefix, usually the parent struct name
+	path   string // Go path of the struct, Conf.Redis
 	find   string // lookup some key
+	// foundPath is set to the Go path of the field find found
+	foundPath *string
 	// struct types currently being walked, so that a self referencing
 	// type (type Node struct{ Next *Node }) stops instead of recursing
 	// until the stack blows up
@@ -100,6 +159,71 @@ type roOption struct {
 	// asked for, and testing the result for zero cannot tell the two
 	// apart.
 	filled *bool
+	// report collects how every key was resolved, nil when nobody asked
+	report *[]Resolution
+	// errs collects the conversion errors instead of stopping at the
+	// first one, see Advance.CollectErrors
+	errs *[]error
+	// missing collects the required keys left without a value
+	missing *[]error
+}
+
+// fail reports the error of a key. It is collected, and nil returned,
+// when the walk goes on after an error, see Advance.CollectErrors.
+func (o roOption) fail(err error) error {
+	if o.errs == nil {
+		return err
+	}
+	*o.errs = append(*o.errs, err)
+	return nil
+}
+
+// validateSection calls the Validate method of a section once all of its
+// fields, nested sections included, are filled. A section with a required
+// key missing, one collected past since, is not validated, its rules are
+// beside the point. Unless every error is collected anyway, a failed
+// rule goes with the missing keys rather than ending the walk, so that
+// Parse still reports all of them.
+func (e *ECP) validateSection(opts roOption, section reflect.Value, key, path string, since int) error {
+	if opts.find != "" || opts.missed() > since {
+		return nil
+	}
+
+	var v Validator
+	if section.CanAddr() {
+		v, _ = section.Addr().Interface().(Validator)
+	} else {
+		v, _ = section.Interface().(Validator)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		err := &ParseError{Key: key, Path: path, Type: section.Type(), Err: err}
+		if opts.errs == nil && opts.missing != nil {
+			*opts.missing = append(*opts.missing, err)
+			return nil
+		}
+		return opts.fail(err)
+	}
+	return nil
+}
+
+// missed is the number of missing keys collected so far
+func (o roOption) missed() int {
+	if o.missing == nil {
+		return 0
+	}
+	return len(*o.missing)
+}
+
+// section returns the options to walk a nested section with, sharing
+// everything collected along the walk
+func (o roOption) section(target interface{}, prefix, path string) roOption {
+	o.target = target
+	o.prefix = prefix
+	o.path = path
+	return o
 }
 
 // markFilled records that a field was assigned during this walk
@@ -109,6 +233,23 @@ func (o roOption) markFilled() {
 	}
 }
 
+// record adds the resolution of a key to the report, if there is one
+func (e *ECP) record(opts roOption, r Resolution, tag reflect.StructTag, field reflect.Value) {
+	if opts.report == nil {
+		return
+	}
+	r.Value = e.formatValue(tag, field)
+	*opts.report = append(*opts.report, r)
+}
+
+// joinPath appends a field name to the Go path of its struct
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
 func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 
 	rValue := toValue(opts.target)
@@ -116,6 +257,9 @@ func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 		return reflect.Value{}, fmt.Errorf("config must be a struct or a non-nil pointer to a struct, got %v", opts.target)
 	}
 	rType := rValue.Type()
+	if opts.path == "" {
+		opts.path = rType.Name()
+	}
 
 	if opts.visiting == nil {
 		opts.visiting = make(map[reflect.Type]bool, 1)
@@ -129,6 +273,7 @@ func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 		}
 
 		info := e.getAll(getAllOpt{rType, rValue, index, opts.prefix})
+		path := joinPath(opts.path, rType.Field(index).Name)
 		field := info.value
 		structName := info.parent
 		keyName := info.key
@@ -139,10 +284,14 @@ func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 			continue
 		}
 
-		section := isSection(field)
+		section := isSection(field) || isSectionList(field.Type()) ||
+			isSectionMap(field.Type())
 
 		if opts.find != "" {
-			if opts.find == keyName {
+			if opts.find == keyName || contains(info.aliases, opts.find) {
+				if opts.foundPath != nil {
+					*opts.foundPath = path
+				}
 				return field, nil
 			}
 			// skip this field
@@ -151,7 +300,21 @@ func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 			}
 		}
 
-		v, exist := e.LookupValue(keyName)
+		// readKey is the name the value was read from, keyName or one
+		// of its aliases
+		readKey := keyName
+		v, source, exist := e.lookup(keyName)
+		if v == "" && !section {
+			for _, alias := range info.aliases {
+				if av, as, ok := e.lookup(alias); ok && av != "" {
+					readKey, v, source, exist = alias, av, as, true
+					if e.Advance.OnDeprecated != nil {
+						e.Advance.OnDeprecated(alias, keyName)
+					}
+					break
+				}
+			}
+		}
 		if opts.setDef && !exist {
 			v = defaultV
 		}
@@ -163,80 +326,120 @@ func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 			}
 		}
 
-		kind := field.Kind()
 		if v == "" && !section {
+			origin := Unset
+			if !field.IsZero() {
+				origin = Preset
+			}
+			e.record(opts, Resolution{Key: keyName, Path: path, Origin: origin}, info.tag, field)
+			if origin == Unset && info.required && opts.missing != nil {
+				*opts.missing = append(*opts.missing, &ParseError{
+					Key:  keyName,
+					Path: path,
+					Type: field.Type(),
+					Err:  ErrRequired,
+				})
+			}
 			continue
 		}
+		if exist && !section && e.Advance.OnResolve != nil {
+			e.Advance.OnResolve(readKey, source)
+		}
+
+		resolution := Resolution{Key: keyName, Path: path, Origin: FromDefault}
+		if exist {
+			resolution.Origin = FromSource
+			resolution.Source = source
+		}
 
 		// set value via self-defined function
 		if opts.find == "" && e.Advance.SetValue != nil &&
 			e.Advance.SetValue(info.tag, field, v) {
 			opts.markFilled()
+			if !section {
+				e.record(opts, resolution, info.tag, field)
+			}
 			continue
 		}
 
-		switch kind {
-		case reflect.Struct:
-			prefix := e.BuildKey(opts.prefix, structName, info.tag)
-			found, err := e.rangeOver(roOption{
-				target:   field,
-				setDef:   opts.setDef,
-				prefix:   prefix,
-				find:     opts.find,
-				visiting: opts.visiting,
-				filled:   opts.filled,
-			})
+		switch {
+		case field.Kind() == reflect.Struct && section:
+			prefix := e.key(opts.prefix, structName, info.tag)
+			since := opts.missed()
+			found, err := e.rangeOver(opts.section(field, prefix, path))
 			if err != nil {
 				return reflect.Value{}, err
 			}
 			if opts.find != "" && found.IsValid() {
 				return found, nil
 			}
+			if err := e.validateSection(opts, field, prefix, path, since); err != nil {
+				return field, err
+			}
 
-		case reflect.Ptr:
-			if section {
-				found, err := e.rangeOverPointer(field, structName, info.tag, opts)
-				if err != nil {
-					return reflect.Value{}, err
-				}
-				if opts.find != "" && found.IsValid() {
-					return found, nil
-				}
-				continue
+		case field.Kind() == reflect.Slice && section:
+			found, err := e.rangeOverList(field, structName, path, info.tag, opts)
+			if err != nil {
+				return reflect.Value{}, err
 			}
-			// only set the default value to a nil pointer, but still
-			// allow an existing environment value to overwrite a set one
-			if !field.IsNil() && !exist {
-				continue
+			if opts.find != "" && found.IsValid() {
+				return found, nil
 			}
-			if err := e.setPointer(field, v); err != nil {
-				return field, fmt.Errorf("convert %s error: %w", keyName, err)
+
+		case field.Kind() == reflect.Map && section:
+			found, err := e.rangeOverMap(field, structName, path, info.tag, opts)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if opts.find != "" && found.IsValid() {
+				return found, nil
 			}
-			opts.markFilled()
 
-		case reflect.Slice:
-			if !field.IsNil() && !exist {
-				continue
+		case section:
+			found, err := e.rangeOverPointer(field, structName, path, info.tag, opts)
+			if err != nil {
+				return reflect.Value{}, err
 			}
-			if err := e.parseSlice(v, field); err != nil {
-				return field, fmt.Errorf("convert %s error: %w", keyName, err)
+			if opts.find != "" && found.IsValid() {
+				return found, nil
 			}
-			opts.markFilled()
+
+		// a value already set by the caller wins over the default, but
+		// never over an environment value. For a pointer, a slice or a
+		// map, that means the default only goes to a nil one.
+		case !exist && !field.IsZero():
+			resolution.Origin = Preset
+			e.record(opts, resolution, info.tag, field)
 
 		default:
-			// a value already set by the caller wins over the default,
-			// but never over an environment value
-			if !exist && !field.IsZero() {
-				continue
+			// keep the previous value, to put it back if the new one
+			// turns out to be invalid
+			previous := reflect.New(field.Type()).Elem()
+			previous.Set(field)
+
+			err := e.setField(info.tag, field, v)
+			if err == nil {
+				if err = e.validate(info.tag, field); err != nil {
+					field.Set(previous)
+				}
 			}
-			value, err := expandNumber(field.Type(), v)
 			if err != nil {
-				return field, fmt.Errorf("convert %s error: %w", keyName, err)
-			}
-			if err := setValue(field, value); err != nil {
-				return field, fmt.Errorf("convert %s error: %w", keyName, err)
+				err = opts.fail(&ParseError{
+					Key:    readKey,
+					Path:   path,
+					Type:   field.Type(),
+					Value:  v,
+					Source: resolution.Source,
+					Err:    err,
+				})
+				if err != nil {
+					return field, err
+				}
+				// keep going, the field is left as it was
+				continue
 			}
 			opts.markFilled()
+			e.record(opts, resolution, info.tag, field)
 		}
 
 	}
@@ -248,7 +451,7 @@ func (e *ECP) rangeOver(opts roOption) (reflect.Value, error) {
 // only allocated when one of its fields was actually assigned, so that an
 // untouched optional section stays nil while a section explicitly asked
 // for is allocated even when every value in it is zero.
-func (e *ECP) rangeOverPointer(field reflect.Value, structName string,
+func (e *ECP) rangeOverPointer(field reflect.Value, structName, path string,
 	tag reflect.StructTag, opts roOption) (reflect.Value, error) {
 
 	elemType := field.Type().Elem()
@@ -267,18 +470,30 @@ func (e *ECP) rangeOverPointer(field reflect.Value, structName string,
 	}
 
 	filled := false
-	found, err := e.rangeOver(roOption{
-		target:   target.Elem(),
-		setDef:   opts.setDef,
-		prefix:   e.BuildKey(opts.prefix, structName, tag),
-		find:     opts.find,
-		visiting: opts.visiting,
-		filled:   &filled,
-	})
+	var missing []error
+	prefix := e.key(opts.prefix, structName, tag)
+	sectionOpts := opts.section(target.Elem(), prefix, path)
+	sectionOpts.filled = &filled
+	if opts.missing != nil {
+		sectionOpts.missing = &missing
+	}
+	found, err := e.rangeOver(sectionOpts)
 	if err != nil {
 		return reflect.Value{}, err
 	}
 
+	// the required keys of an optional section are only required once
+	// the section exists, and so are its own rules
+	if filled || !field.IsNil() {
+		since := opts.missed()
+		if opts.missing != nil {
+			*opts.missing = append(*opts.missing, missing...)
+		}
+		if err := e.validateSection(opts, target.Elem(), prefix, path, since); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
 	if !filled {
 		return found, nil
 	}
@@ -291,6 +506,287 @@ func (e *ECP) rangeOverPointer(field reflect.Value, structName string,
 	return found, nil
 }
 
+// rangeOverList walks a list of sections, []Server or []*Server, element
+// i being the section of prefix UPSTREAMS_i. The elements the caller set
+// are walked like any other section, and the list grows for as long as
+// the sources have a key for the next index.
+func (e *ECP) rangeOverList(field reflect.Value, structName, path string,
+	tag reflect.StructTag, opts roOption) (reflect.Value, error) {
+
+	elemType := sectionType(field.Type())
+	if opts.visiting[elemType] {
+		// cyclic type, stop here
+		return reflect.Value{}, nil
+	}
+	prefix := e.key(opts.prefix, structName, tag)
+
+	list := field
+	if opts.find == "" && field.CanSet() {
+		n := field.Len()
+		if indexes, ok := e.indexes(elemType, prefix); ok {
+			for n < maxListLength && indexes[n] {
+				n++
+			}
+		} else {
+			for n < maxListLength && e.hasKeys(elemType, e.indexKey(prefix, n)) {
+				n++
+			}
+		}
+		if n > field.Len() {
+			list = reflect.MakeSlice(field.Type(), n, n)
+			reflect.Copy(list, field)
+		}
+	}
+
+	for i := 0; i < list.Len(); i++ {
+		elem := list.Index(i)
+		elemPrefix := e.indexKey(prefix, i)
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				if opts.find != "" || !elem.CanSet() {
+					continue
+				}
+				elem.Set(reflect.New(elemType))
+			}
+			elem = elem.Elem()
+		}
+
+		since := opts.missed()
+		found, err := e.rangeOver(opts.section(elem, elemPrefix, elemPath))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if found.IsValid() {
+			return found, nil
+		}
+		if err := e.validateSection(opts, elem, elemPrefix, elemPath, since); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	if list.Len() != field.Len() {
+		field.Set(list)
+		opts.markFilled()
+	}
+	return reflect.Value{}, nil
+}
+
+// indexKey is the prefix of element i of a list of sections
+func (e *ECP) indexKey(prefix string, i int) string {
+	return e.key(prefix, strconv.Itoa(i), "")
+}
+
+// hasKeys reports whether a source has a value for any key of the
+// section at prefix. The keys an env tag sets regardless of the prefix do
+// not count, they would be there for every index.
+func (e *ECP) hasKeys(typ reflect.Type, prefix string) bool {
+	for _, f := range e.keys(reflect.New(typ).Elem(), prefix) {
+		if !strings.HasPrefix(f.Key, prefix) {
+			continue
+		}
+		if _, _, exist := e.lookup(f.Key); exist {
+			return true
+		}
+	}
+	return false
+}
+
+// indexes returns the indexes of a list of sections with at least one
+// key set, when the sources can enumerate all of their keys. It is the
+// set hasKeys would probe one index at a time.
+func (e *ECP) indexes(typ reflect.Type, prefix string) (map[int]bool, bool) {
+	segments, complete := e.segments(typ, prefix)
+	if !complete {
+		return nil, false
+	}
+	indexes := make(map[int]bool, len(segments))
+	for _, segment := range segments {
+		// 01 is not the key of index 1
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && strconv.Itoa(i) == segment {
+			indexes[i] = true
+		}
+	}
+	return indexes, true
+}
+
+// rangeOverMap walks a map of sections, map[string]DB or map[string]*DB,
+// the entry "primary" being the section of prefix DB_PRIMARY. The entries
+// the caller set are walked like any other section, and an entry is added
+// for every segment found in the place of PRIMARY among the keys the
+// sources enumerate.
+//
+// A new entry is keyed by the segment as it is written in the key.
+func (e *ECP) rangeOverMap(field reflect.Value, structName, path string,
+	tag reflect.StructTag, opts roOption) (reflect.Value, error) {
+
+	typ := field.Type()
+	elemType := sectionType(typ)
+	if opts.visiting[elemType] {
+		// cyclic type, stop here
+		return reflect.Value{}, nil
+	}
+	prefix := e.key(opts.prefix, structName, tag)
+
+	// the entries to walk, by the prefix of their keys
+	entries := map[string]reflect.Value{}
+	iter := field.MapRange()
+	for iter.Next() {
+		entries[e.key(prefix, e.formatValue("", iter.Key()), "")] = iter.Key()
+	}
+	if opts.find == "" && field.CanSet() {
+		segments, _ := e.segments(elemType, prefix)
+		for _, segment := range segments {
+			entryPrefix := e.key(prefix, segment, "")
+			if _, ok := entries[entryPrefix]; ok {
+				continue
+			}
+			key := reflect.New(typ.Key()).Elem()
+			if err := setValue(key, segment); err != nil {
+				err = opts.fail(&ParseError{
+					Key:   entryPrefix,
+					Path:  path,
+					Type:  typ.Key(),
+					Value: segment,
+					Err:   err,
+				})
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				continue
+			}
+			entries[entryPrefix] = key
+		}
+	}
+	if len(entries) == 0 {
+		return reflect.Value{}, nil
+	}
+
+	// walk the entries in a stable order, the one of a map is random
+	prefixes := make([]string, 0, len(entries))
+	for p := range entries {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	m := field
+	if m.IsNil() {
+		m = reflect.MakeMap(typ)
+	}
+	for _, entryPrefix := range prefixes {
+		key := entries[entryPrefix]
+		entryPath := fmt.Sprintf("%s[%s]", path, e.formatValue("", key))
+
+		// a map value is not addressable, the section is filled through
+		// a copy of it or through the pointer it is
+		value := m.MapIndex(key)
+		var entry, section reflect.Value
+		if typ.Elem().Kind() == reflect.Ptr {
+			entry = value
+			if !value.IsValid() || value.IsNil() {
+				if opts.find != "" {
+					continue
+				}
+				entry = reflect.New(elemType)
+			}
+			section = entry.Elem()
+		} else {
+			entry = reflect.New(elemType).Elem()
+			if value.IsValid() {
+				entry.Set(value)
+			}
+			section = entry
+		}
+
+		since := opts.missed()
+		found, err := e.rangeOver(opts.section(section, entryPrefix, entryPath))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if found.IsValid() {
+			return found, nil
+		}
+		if opts.find != "" {
+			continue
+		}
+		if err := e.validateSection(opts, section, entryPrefix, entryPath, since); err != nil {
+			return reflect.Value{}, err
+		}
+		m.SetMapIndex(key, entry)
+	}
+
+	if field.IsNil() && m.Len() != 0 {
+		field.Set(m)
+		opts.markFilled()
+	}
+	return reflect.Value{}, nil
+}
+
+// segments finds the entries of a map of sections among the enumerated
+// keys: the segments standing where PRIMARY does in DB_PRIMARY_HOST. A
+// key only counts if it can be looked up too, in case the enumeration
+// does not match the lookup. complete tells whether every source could
+// enumerate its keys.
+//
+// A key may end with the suffix of several fields: DB_PRIMARY_READ_PORT
+// is the READ_PORT of PRIMARY or the PORT of PRIMARY_READ. The longest
+// suffix wins, there would be no way to set READ_PORT otherwise.
+//
+// A segment BuildKey writes another way, read_replica in
+// DB_read_replica_HOST, is skipped: its entry would be keyed
+// DB_READ_REPLICA, and none of its keys read.
+func (e *ECP) segments(elemType reflect.Type, prefix string) (segments []string, complete bool) {
+	template := e.key(prefix, segmentMarker, "")
+	head, _, _ := strings.Cut(template, segmentMarker)
+
+	// the suffixes of the fields, by what comes before the segment
+	suffixes := map[string][]string{}
+	befores := []string{}
+	for _, f := range e.keys(reflect.New(elemType).Elem(), template) {
+		before, after, found := strings.Cut(f.Key, segmentMarker)
+		if !found {
+			// an env tag, the same key for every entry
+			continue
+		}
+		if _, ok := suffixes[before]; !ok {
+			befores = append(befores, before)
+		}
+		suffixes[before] = append(suffixes[before], after)
+	}
+
+	complete = true
+	seen := map[string]bool{}
+	for _, before := range befores {
+		names, ok := e.enumerate(before)
+		complete = complete && ok
+		for _, name := range names {
+			if !strings.HasPrefix(name, before) {
+				continue
+			}
+			longest, found := "", false
+			for _, after := range suffixes[before] {
+				if len(name) > len(before)+len(after) && strings.HasSuffix(name, after) &&
+					(!found || len(after) > len(longest)) {
+					longest, found = after, true
+				}
+			}
+			if !found {
+				continue
+			}
+			segment := name[len(before) : len(name)-len(longest)]
+			if seen[segment] || e.key(prefix, segment, "") != head+segment {
+				continue
+			}
+			if _, _, exist := e.lookup(name); exist {
+				seen[segment] = true
+				segments = append(segments, segment)
+			}
+		}
+	}
+	return segments, complete
+}
+
 // parseScientific rewrites "1e3" and "1,000" into a plain integer literal
 func parseScientific(v string) (string, error) {
 	v = strings.ReplaceAll(v, ",", "")
@@ -1,6 +1,10 @@
 package ecp
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestParseScientific(t *testing.T) {
 	testCases := map[string]string{
@@ -30,3 +34,237 @@ func TestParseScientific(t *testing.T) {
 		}
 	}
 }
+
+func TestSectionList(t *testing.T) {
+	type server struct {
+		Host   string `default:"localhost"`
+		Port   int    `default:"80"`
+		Global string `env:"LIST_GLOBAL"`
+	}
+	type conf struct {
+		Upstreams []server  `yaml:"upstreams"`
+		Backups   []*server `yaml:"backups"`
+		Nothing   []server  `yaml:"nothing"`
+	}
+
+	withEnv(t, "UPSTREAMS_0_HOST", "a.local")
+	withEnv(t, "UPSTREAMS_1_PORT", "8080")
+	withEnv(t, "UPSTREAMS_3_HOST", "after.a.gap")
+	withEnv(t, "BACKUPS_0_HOST", "b.local")
+	withEnv(t, "LIST_GLOBAL", "everywhere")
+
+	c := &conf{}
+	report, err := ParseWithReport(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Upstreams) != 2 {
+		t.Fatalf("upstreams: %+v", c.Upstreams)
+	}
+	if c.Upstreams[0] != (server{"a.local", 80, "everywhere"}) ||
+		c.Upstreams[1] != (server{"localhost", 8080, "everywhere"}) {
+		t.Errorf("upstreams: %+v", c.Upstreams)
+	}
+	if len(c.Backups) != 1 || c.Backups[0].Host != "b.local" {
+		t.Errorf("backups: %+v", c.Backups)
+	}
+	if c.Nothing != nil {
+		t.Errorf("a list without any key should stay nil: %+v", c.Nothing)
+	}
+
+	var paths []string
+	for _, r := range report {
+		if r.Key == "UPSTREAMS_1_PORT" {
+			paths = append(paths, r.Path)
+		}
+	}
+	if strings.Join(paths, " ") != "conf.Upstreams[1].Port" {
+		t.Errorf("paths: %v", paths)
+	}
+
+	// the caller's elements are walked, and the list goes on from there:
+	// UPSTREAMS_3 is no longer after a gap
+	t.Run("elements set by the caller", func(t *testing.T) {
+		c := &conf{Upstreams: []server{{Host: "mine", Port: 1}, {}, {}}}
+		if err := Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Upstreams) != 4 || c.Upstreams[0].Host != "a.local" ||
+			c.Upstreams[0].Port != 1 || c.Upstreams[2].Host != "localhost" ||
+			c.Upstreams[3].Host != "after.a.gap" {
+			t.Errorf("upstreams: %+v", c.Upstreams)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		if v, err := GetInt64(c, "UPSTREAMS_1_PORT"); err != nil || v != 8080 {
+			t.Errorf("got %v %v", v, err)
+		}
+		if v, err := GetString(*c, "BACKUPS_0_HOST"); err != nil || v != "b.local" {
+			t.Errorf("got %v %v", v, err)
+		}
+		if _, err := Get(c, "UPSTREAMS_2_PORT"); err == nil {
+			t.Error("there is no third upstream")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list := strings.Join(List(conf{}), " ")
+		for _, want := range []string{"UPSTREAMS_<N>_HOST=localhost",
+			"UPSTREAMS_<N>_PORT=80", "BACKUPS_<N>_HOST=localhost"} {
+			if !strings.Contains(list, want) {
+				t.Errorf("missing %s in %s", want, list)
+			}
+		}
+	})
+}
+
+func TestSectionMap(t *testing.T) {
+	type db struct {
+		Host string `default:"localhost"`
+		Port int    `default:"5432"`
+	}
+	type conf struct {
+		DB      map[string]db  `yaml:"db"`
+		Caches  map[string]*db `yaml:"caches"`
+		Shards  map[int]db     `yaml:"shards"`
+		Nothing map[string]db  `yaml:"nothing"`
+	}
+
+	withEnv(t, "DB_PRIMARY_HOST", "p.local")
+	withEnv(t, "DB_REPLICA_PORT", "5433")
+	withEnv(t, "CACHES_HOT_HOST", "hot.local")
+	withEnv(t, "SHARDS_2_PORT", "6000")
+
+	c := &conf{}
+	report, err := ParseWithReport(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.DB) != 2 || c.DB["PRIMARY"] != (db{"p.local", 5432}) ||
+		c.DB["REPLICA"] != (db{"localhost", 5433}) {
+		t.Errorf("db: %+v", c.DB)
+	}
+	if len(c.Caches) != 1 || c.Caches["HOT"] == nil || c.Caches["HOT"].Host != "hot.local" {
+		t.Errorf("caches: %+v", c.Caches)
+	}
+	if len(c.Shards) != 1 || c.Shards[2].Port != 6000 {
+		t.Errorf("shards: %+v", c.Shards)
+	}
+	if c.Nothing != nil {
+		t.Errorf("a map without any key should stay nil: %+v", c.Nothing)
+	}
+
+	var paths []string
+	for _, r := range report {
+		if r.Key == "DB_REPLICA_PORT" {
+			paths = append(paths, r.Path)
+		}
+	}
+	if strings.Join(paths, " ") != "conf.DB[REPLICA].Port" {
+		t.Errorf("paths: %v", paths)
+	}
+
+	// an entry set by the caller keeps its own key
+	t.Run("entries set by the caller", func(t *testing.T) {
+		c := &conf{DB: map[string]db{"primary": {Port: 1}, "other": {}}}
+		if err := Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.DB) != 3 || c.DB["primary"] != (db{"p.local", 1}) ||
+			c.DB["other"] != (db{"localhost", 5432}) || c.DB["REPLICA"].Port != 5433 {
+			t.Errorf("db: %+v", c.DB)
+		}
+	})
+
+	// DB_PRIMARY_READ_PORT is the ReadPort of PRIMARY, not the Port of
+	// PRIMARY_READ
+	t.Run("longest suffix", func(t *testing.T) {
+		type replica struct {
+			Port     int
+			ReadPort int
+		}
+		type nested struct {
+			Port int
+			Read struct{ Port int }
+		}
+		env := Dotenv{"APP_DB_PRIMARY_READ_PORT": "5", "APP_DB_PRIMARY_PORT": "4"}
+
+		e := New()
+		e.Advance.KeyStyle = SnakeKeys
+		e.Sources = []Source{env.Source("env")}
+		snake := &struct{ DB map[string]replica }{}
+		if err := e.Parse(snake, "APP"); err != nil {
+			t.Fatal(err)
+		}
+		if len(snake.DB) != 1 || snake.DB["PRIMARY"] != (replica{4, 5}) {
+			t.Errorf("snake: %+v", snake.DB)
+		}
+
+		e.Advance.KeyStyle = LegacyKeys
+		legacy := &struct{ DB map[string]nested }{}
+		if err := e.Parse(legacy, "APP"); err != nil {
+			t.Fatal(err)
+		}
+		if len(legacy.DB) != 1 || legacy.DB["PRIMARY"].Port != 4 || legacy.DB["PRIMARY"].Read.Port != 5 {
+			t.Errorf("legacy: %+v", legacy.DB)
+		}
+	})
+
+	// an entry keyed the way BuildKey would not write it has no keys
+	t.Run("rewritten segment", func(t *testing.T) {
+		type db struct {
+			Host string
+			Port int `default:"5432"`
+		}
+		env := Dotenv{
+			"DB_read_replica_HOST": "replica.local",
+			"DB_readReplica_HOST":  "replica.local",
+			"DB_PRIMARY_HOST":      "primary.local",
+		}
+		for _, style := range []KeyStyle{LegacyKeys, SnakeKeys} {
+			e := New()
+			e.Advance.KeyStyle = style
+			e.Sources = []Source{env.Source("env")}
+			c := &struct {
+				DB map[string]db
+			}{}
+			if err := e.Parse(c); err != nil {
+				t.Fatal(err)
+			}
+			if len(c.DB) != 1 || c.DB["PRIMARY"] != (db{"primary.local", 5432}) {
+				t.Errorf("style %v: %+v", style, c.DB)
+			}
+		}
+	})
+
+	t.Run("bad key", func(t *testing.T) {
+		withEnv(t, "SHARDS_X_PORT", "1")
+		err := Parse(&conf{})
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Key != "SHARDS_X" {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		if v, err := GetString(c, "DB_PRIMARY_HOST"); err != nil || v != "p.local" {
+			t.Errorf("got %v %v", v, err)
+		}
+		if _, err := Get(c, "DB_MISSING_HOST"); err == nil {
+			t.Error("there is no missing entry")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list := strings.Join(List(conf{}), " ")
+		for _, want := range []string{"DB_<KEY>_HOST=localhost",
+			"CACHES_<KEY>_PORT=5432", "SHARDS_<KEY>_HOST=localhost"} {
+			if !strings.Contains(list, want) {
+				t.Errorf("missing %s in %s", want, list)
+			}
+		}
+	})
+}
@@ -233,11 +233,11 @@ func TestBoolDefaultKeepsCallerValue(t *testing.T) {
 // word, now they are reported and never listed
 func TestUnsupportedKinds(t *testing.T) {
 	c := &struct {
-		M map[string]string `env:"REG_MAP"`
+		C chan int `env:"REG_CHAN"`
 	}{}
-	withEnv(t, "REG_MAP", "a=1")
+	withEnv(t, "REG_CHAN", "1")
 	if err := Parse(c); err == nil {
-		t.Error("expected an error for a map field with a value")
+		t.Error("expected an error for a chan field with a value")
 	}
 
 	arr := &struct {
@@ -251,6 +251,9 @@ func TestUnsupportedKinds(t *testing.T) {
 	if list := List(*c); len(list) != 0 {
 		t.Errorf("unsupported kinds should not be listed: %v", list)
 	}
+	if list := List(*arr); len(list) != 0 {
+		t.Errorf("unsupported kinds should not be listed: %v", list)
+	}
 }
 
 // "a  b" used to be split into three elements, the empty one making every
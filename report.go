@@ -0,0 +1,53 @@
+package ecp
+
+// Origin tells where the value of a key came from
+type Origin int
+
+const (
+	// Unset means no value was found for the key, and the field was not
+	// set by the caller either
+	Unset Origin = iota
+	// FromSource means the value was found in a source, the environment
+	// unless Sources says otherwise
+	FromSource
+	// FromDefault means the value is the one of the "default" tag
+	FromDefault
+	// Preset means the value was already set by the caller and no source
+	// overrode it
+	Preset
+)
+
+func (o Origin) String() string {
+	switch o {
+	case FromSource:
+		return "source"
+	case FromDefault:
+		return "default"
+	case Preset:
+		return "preset"
+	}
+	return "unset"
+}
+
+// Resolution is how Parse resolved the value of a key
+type Resolution struct {
+	Key    string // environment key
+	Path   string // Go path of the field, Conf.Redis.Port
+	Value  string // final value, in the syntax Parse reads it in
+	Origin Origin
+	Source string // name of the source, when Origin is FromSource
+}
+
+// ParseWithReport parses the config like Parse does, and reports how
+// every key was resolved, in the order of the fields
+func (e *ECP) ParseWithReport(config interface{}, prefix ...string) ([]Resolution, error) {
+	report := []Resolution{}
+	err := e.parse(config, prefix, &report)
+	return report, err
+}
+
+// ParseWithReport parses the config like Parse does, and reports how
+// every key was resolved, in the order of the fields
+func ParseWithReport(config interface{}, prefix ...string) ([]Resolution, error) {
+	return globalEcp.ParseWithReport(config, prefix...)
+}
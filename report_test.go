@@ -0,0 +1,78 @@
+package ecp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWithReport(t *testing.T) {
+	type redis struct {
+		Host string `default:"localhost"`
+		Port int    `env:"REPORT_REDIS_PORT"`
+	}
+	type Conf struct {
+		Port     int           `env:"REPORT_PORT" default:"80"`
+		Name     string        `env:"REPORT_NAME" default:"app"`
+		Timeout  time.Duration `env:"REPORT_TIMEOUT" default:"1m"`
+		Tags     []string      `env:"REPORT_TAGS"`
+		Optional *redis        `yaml:"optional"`
+		Redis    redis         `yaml:"redis"`
+		Nothing  *int          `env:"REPORT_NOTHING"`
+	}
+
+	withEnv(t, "REPORT_PORT", "8080")
+	withEnv(t, "REPORT_TAGS", "a b")
+
+	c := &Conf{Name: "mine"}
+	report, err := ParseWithReport(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Resolution{
+		{"REPORT_PORT", "Conf.Port", "8080", FromSource, "env"},
+		{"REPORT_NAME", "Conf.Name", "mine", Preset, ""},
+		{"REPORT_TIMEOUT", "Conf.Timeout", "1m0s", FromDefault, ""},
+		{"REPORT_TAGS", "Conf.Tags", "a b", FromSource, "env"},
+		{"OPTIONAL_HOST", "Conf.Optional.Host", "localhost", FromDefault, ""},
+		{"REPORT_REDIS_PORT", "Conf.Optional.Port", "0", Unset, ""},
+		{"REDIS_HOST", "Conf.Redis.Host", "localhost", FromDefault, ""},
+		{"REPORT_REDIS_PORT", "Conf.Redis.Port", "0", Unset, ""},
+		{"REPORT_NOTHING", "Conf.Nothing", "", Unset, ""},
+	}
+	if len(report) != len(want) {
+		t.Fatalf("got %d resolutions, want %d: %+v", len(report), len(want), report)
+	}
+	for i, w := range want {
+		if report[i] != w {
+			t.Errorf("got %+v, want %+v", report[i], w)
+		}
+	}
+
+	if FromSource.String() != "source" || Unset.String() != "unset" {
+		t.Errorf("origin names: %s %s", FromSource, Unset)
+	}
+}
+
+// the report names the source a value was found in
+func TestParseWithReportSources(t *testing.T) {
+	e := New()
+	e.Sources = []Source{
+		Dotenv{"HOST": "file"}.Source(".env"),
+		Dotenv{"HOST": "flag", "PORT": "1"}.Source("flags"),
+	}
+	e.Advance.Precedence = LastWins
+
+	report, err := e.ParseWithReport(&struct {
+		Host string
+		Port int
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report {
+		if r.Origin != FromSource || r.Source != "flags" {
+			t.Errorf("%s: %+v", r.Key, r)
+		}
+	}
+}
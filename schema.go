@@ -0,0 +1,192 @@
+package ecp
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// the syntax of the values setValue takes, for the string form of a
+// number or a boolean
+const (
+	boolPattern  = `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`
+	intPattern   = `^[+-]?[0-9][0-9,]*(\.[0-9]+)?([eE]\+?[0-9]+)?$`
+	uintPattern  = `^\+?[0-9][0-9,]*(\.[0-9]+)?([eE]\+?[0-9]+)?$`
+	floatPattern = `^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`
+)
+
+// JSONSchema describes the environment of a config as a JSON Schema
+// (draft 2020-12), an object with a property per key, for the env block
+// of a compose file or a Kubernetes manifest to be checked offline.
+//
+// A string field is a string, its min and max tags bounding its length.
+// A number or a boolean may be written as such, bounded by min and max,
+// or as a string in the syntax Parse reads. Durations, slices, maps and
+// text types are strings. A oneof tag is an enum, a pattern tag a
+// pattern, a default tag the default. The keys of a list or a map of
+// sections are pattern properties, and an alias is a deprecated
+// property. A key is only required when Parse would fail without it: a
+// required key without a default, outside of an optional section.
+func (e *ECP) JSONSchema(config interface{}, prefix ...string) ([]byte, error) {
+	properties := map[string]interface{}{}
+	patternProperties := map[string]interface{}{}
+	required := []string{}
+
+	value := toValue(config)
+	title := ""
+	if value.IsValid() {
+		title = value.Type().Name()
+	}
+
+	for _, f := range e.Describe(config, prefix...) {
+		schema := e.fieldSchema(f)
+		if strings.Contains(f.Key, indexPlaceholder) || strings.Contains(f.Key, keyPlaceholder) {
+			patternProperties[keyMatcher(f.Key).String()] = schema
+		} else {
+			properties[f.Key] = schema
+		}
+		for _, alias := range f.Aliases {
+			deprecated := map[string]interface{}{"deprecated": true}
+			for k, v := range schema {
+				deprecated[k] = v
+			}
+			properties[alias] = deprecated
+		}
+		if f.Required && f.Default == "" && !f.Optional {
+			required = append(required, f.Key)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": properties,
+	}
+	if title != "" {
+		schema["title"] = title
+	}
+	if len(patternProperties) != 0 {
+		schema["patternProperties"] = patternProperties
+	}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// fieldSchema is the schema of the value of a key
+func (e *ECP) fieldSchema(f Field) map[string]interface{} {
+	typ := f.Type
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	tag := f.Tag
+
+	schema := map[string]interface{}{"type": "string"}
+	if f.Description != "" {
+		schema["description"] = f.Description
+	}
+	if f.Default != "" {
+		schema["default"] = f.Default
+	}
+
+	// the JSON value of a literal of the kind, nil when it is not one
+	literal := func(s string) interface{} { return nil }
+	bounded := false
+	switch kind := typ.Kind(); {
+	case typ == durationType || unmarshalsText(typ):
+
+	case kind == reflect.String:
+		for bound, keyword := range map[string]string{"min": "minLength", "max": "maxLength"} {
+			if n, err := strconv.Atoi(tag.Get(bound)); err == nil {
+				schema[keyword] = n
+			}
+		}
+
+	case kind == reflect.Bool:
+		schema["type"] = []string{"boolean", "string"}
+		schema["pattern"] = boolPattern
+		literal = func(s string) interface{} {
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+			return nil
+		}
+
+	case kind == reflect.Int || kind == reflect.Int8 || kind == reflect.Int16 ||
+		kind == reflect.Int32 || kind == reflect.Int64:
+		schema["type"] = []string{"integer", "string"}
+		schema["pattern"] = intPattern
+		literal = func(s string) interface{} {
+			if expanded, err := parseScientific(s); err == nil {
+				if n, err := strconv.ParseInt(expanded, 10, 64); err == nil {
+					return n
+				}
+			}
+			return nil
+		}
+		bounded = true
+
+	case kind == reflect.Uint || kind == reflect.Uint8 || kind == reflect.Uint16 ||
+		kind == reflect.Uint32 || kind == reflect.Uint64:
+		schema["type"] = []string{"integer", "string"}
+		schema["pattern"] = uintPattern
+		schema["minimum"] = 0
+		literal = func(s string) interface{} {
+			if expanded, err := parseScientific(s); err == nil {
+				if n, err := strconv.ParseUint(expanded, 10, 64); err == nil {
+					return n
+				}
+			}
+			return nil
+		}
+		bounded = true
+
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		schema["type"] = []string{"number", "string"}
+		schema["pattern"] = floatPattern
+		literal = func(s string) interface{} {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+			return nil
+		}
+		bounded = true
+	}
+
+	if bounded {
+		for bound, keyword := range map[string]string{"min": "minimum", "max": "maximum"} {
+			if limit := literal(tag.Get(bound)); limit != nil {
+				schema[keyword] = limit
+			}
+		}
+	}
+
+	// a single value, not the elements of a slice
+	if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+		return schema
+	}
+	if oneOf, ok := tag.Lookup("oneof"); ok {
+		enum := []interface{}{}
+		for _, v := range strings.Fields(oneOf) {
+			enum = append(enum, v)
+			if l := literal(v); l != nil {
+				enum = append(enum, l)
+			}
+		}
+		schema["enum"] = enum
+	}
+	if pattern, ok := tag.Lookup("pattern"); ok {
+		if _, err := regexp.Compile(pattern); err == nil {
+			schema["pattern"] = "^(?:" + pattern + ")$"
+		}
+	}
+	return schema
+}
+
+// JSONSchema describes the environment of a config, see ECP.JSONSchema
+func JSONSchema(config interface{}, prefix ...string) ([]byte, error) {
+	return globalEcp.JSONSchema(config, prefix...)
+}
@@ -0,0 +1,86 @@
+package ecp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONSchema(t *testing.T) {
+	type SchemaConf struct {
+		Port    int           `default:"8080" min:"1" max:"65535" required:"true" desc:"port"`
+		Level   string        `oneof:"debug info" default:"info"`
+		Token   string        `required:"true"`
+		Name    string        `min:"2" pattern:"[a-z]+"`
+		Debug   bool          `env:"SCHEMA_DEBUG,SCHEMA_VERBOSE"`
+		Ratio   float64       `max:"1.5"`
+		Workers uint8         `oneof:"1 2 4"`
+		Timeout time.Duration `default:"1s"`
+		Hosts   []string
+		TLS     *struct {
+			Cert string `required:"true"`
+		} `yaml:"tls"`
+		Upstreams []struct{ Host string }
+	}
+
+	content, err := JSONSchema(SchemaConf{}, "APP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	// through JSON, to compare with what a validator would read
+	decode := func(v string) interface{} {
+		var out interface{}
+		if err := json.Unmarshal([]byte(v), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	want := map[string]string{
+		"APP_PORT": `{"type":["integer","string"],"pattern":"` + jsonEscape(intPattern) + `",
+			"minimum":1,"maximum":65535,"default":"8080","description":"port"}`,
+		"APP_LEVEL":    `{"type":"string","enum":["debug","info"],"default":"info"}`,
+		"APP_TOKEN":    `{"type":"string"}`,
+		"APP_NAME":     `{"type":"string","minLength":2,"pattern":"^(?:[a-z]+)$"}`,
+		"SCHEMA_DEBUG": `{"type":["boolean","string"],"pattern":"` + jsonEscape(boolPattern) + `"}`,
+		"SCHEMA_VERBOSE": `{"type":["boolean","string"],"pattern":"` + jsonEscape(boolPattern) + `",
+			"deprecated":true}`,
+		"APP_RATIO": `{"type":["number","string"],"pattern":"` + jsonEscape(floatPattern) + `","maximum":1.5}`,
+		"APP_WORKERS": `{"type":["integer","string"],"pattern":"` + jsonEscape(uintPattern) + `",
+			"minimum":0,"enum":["1",1,"2",2,"4",4]}`,
+		"APP_TIMEOUT":  `{"type":"string","default":"1s"}`,
+		"APP_HOSTS":    `{"type":"string"}`,
+		"APP_TLS_CERT": `{"type":"string"}`,
+	}
+	properties := schema["properties"].(map[string]interface{})
+	if len(properties) != len(want) {
+		t.Errorf("properties: %v", properties)
+	}
+	for key, v := range want {
+		if !reflect.DeepEqual(properties[key], decode(v)) {
+			t.Errorf("%s: got %v, want %s", key, properties[key], v)
+		}
+	}
+
+	// APP_PORT has a default, Parse does not need it
+	if !reflect.DeepEqual(schema["required"], decode(`["APP_TOKEN"]`)) {
+		t.Errorf("required: %v", schema["required"])
+	}
+	if !reflect.DeepEqual(schema["patternProperties"], decode(`{"^APP_UPSTREAMS_[0-9]+_HOST$":{"type":"string"}}`)) {
+		t.Errorf("pattern properties: %v", schema["patternProperties"])
+	}
+	if schema["$schema"] != "https://json-schema.org/draft/2020-12/schema" ||
+		schema["type"] != "object" || schema["title"] != "SchemaConf" {
+		t.Errorf("schema: %v", schema)
+	}
+}
+
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
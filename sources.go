@@ -0,0 +1,92 @@
+package ecp
+
+// Source is a named place values are looked up from, the name is what
+// Advance.OnResolve reports a key was resolved from
+type Source struct {
+	Name   string
+	Lookup LookupValueFunc
+	// Enumerate, optional, lists the keys of the source. Without it
+	// the keys of a map of sections cannot be found in the source, and
+	// a list of sections is probed index by index.
+	Enumerate EnumerateFunc
+}
+
+// Precedence decides which source wins a key defined by several of them
+type Precedence int
+
+const (
+	// FirstWins takes the value of the first source defining the key
+	FirstWins Precedence = iota
+	// LastWins takes the value of the last one, the way a later file
+	// overrides an earlier one
+	LastWins
+)
+
+// envSourceName is the name LookupValue is reported under when there are
+// no Sources
+const envSourceName = "env"
+
+// EnvSource reads the environment
+var EnvSource = Source{
+	Name:      envSourceName,
+	Lookup:    lookupValueFromEnv,
+	Enumerate: enumerateEnv,
+}
+
+// Source names the file as a source
+func (d Dotenv) Source(name string) Source {
+	return Source{Name: name, Lookup: d.Lookup, Enumerate: d.Enumerate}
+}
+
+// lookup finds the value of a key in the Sources, following
+// Advance.Precedence, or through LookupValue when there are none. It also
+// returns the name of the source the value came from.
+func (e *ECP) lookup(key string) (value, source string, exist bool) {
+	if len(e.Sources) == 0 {
+		value, exist = e.LookupValue(key)
+		return value, envSourceName, exist
+	}
+
+	for i := range e.Sources {
+		s := e.Sources[i]
+		if e.Advance.Precedence == LastWins {
+			s = e.Sources[len(e.Sources)-1-i]
+		}
+		if value, exist = s.Lookup(key); exist {
+			return value, s.Name, true
+		}
+	}
+	return "", "", false
+}
+
+// enumerate returns the keys starting with the prefix, through
+// Enumerate or the Sources. complete is only true when every source can
+// enumerate its keys, the keys are otherwise only the ones of the others.
+//
+// Enumerate is never complete: nothing ties it to LookupValue, which a
+// caller may have replaced (by a Dotenv, or env.Over) while leaving it
+// to list the environment.
+func (e *ECP) enumerate(prefix string) (keys []string, complete bool) {
+	if len(e.Sources) == 0 {
+		if e.Enumerate == nil {
+			return nil, false
+		}
+		return e.Enumerate(prefix), false
+	}
+
+	complete = true
+	seen := map[string]bool{}
+	for _, s := range e.Sources {
+		if s.Enumerate == nil {
+			complete = false
+			continue
+		}
+		for _, key := range s.Enumerate(prefix) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, complete
+}
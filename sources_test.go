@@ -0,0 +1,176 @@
+package ecp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSources(t *testing.T) {
+	defaults := Dotenv{"HOST": "localhost", "PORT": "80", "NAME": "default"}
+	file := Dotenv{"PORT": "8080", "NAME": "file"}
+	flags := Dotenv{"NAME": "flag"}
+
+	type conf struct {
+		Host  string
+		Port  int
+		Name  string
+		Debug bool `default:"true"`
+	}
+
+	for _, tc := range []struct {
+		precedence Precedence
+		sources    []Source
+		want       conf
+	}{
+		{
+			FirstWins,
+			[]Source{flags.Source("flags"), file.Source("file"), defaults.Source("defaults")},
+			conf{"localhost", 8080, "flag", true},
+		},
+		{
+			LastWins,
+			[]Source{defaults.Source("defaults"), file.Source("file"), flags.Source("flags")},
+			conf{"localhost", 8080, "flag", true},
+		},
+		{
+			LastWins,
+			[]Source{flags.Source("flags"), file.Source("file"), defaults.Source("defaults")},
+			conf{"localhost", 80, "default", true},
+		},
+	} {
+		resolved := map[string]string{}
+		e := New()
+		e.Sources = tc.sources
+		e.Advance.Precedence = tc.precedence
+		e.Advance.OnResolve = func(key, source string) { resolved[key] = source }
+
+		c := &conf{}
+		if err := e.Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if *c != tc.want {
+			t.Errorf("precedence %d: got %+v, want %+v", tc.precedence, *c, tc.want)
+		}
+
+		// every key but the one left to its default tag has a source
+		want := map[string]string{"HOST": "defaults", "PORT": "file", "NAME": "flags"}
+		if tc.want.Name == "default" {
+			want = map[string]string{"HOST": "defaults", "PORT": "defaults", "NAME": "defaults"}
+		}
+		if len(resolved) != len(want) {
+			t.Errorf("precedence %d: resolved %v, want %v", tc.precedence, resolved, want)
+		}
+		for k, v := range want {
+			if resolved[k] != v {
+				t.Errorf("precedence %d: %s resolved from %q, want %q", tc.precedence, k, resolved[k], v)
+			}
+		}
+	}
+}
+
+// without Sources, LookupValue is still what Parse reads from
+func TestSourcesFallBackToLookupValue(t *testing.T) {
+	withEnv(t, "SRC_ENV_VALUE", "x")
+
+	var sources []string
+	e := New()
+	e.Advance.OnResolve = func(key, source string) {
+		sources = append(sources, key+"@"+source)
+	}
+	c := &struct {
+		Value string `env:"SRC_ENV_VALUE"`
+	}{}
+	if err := e.Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Value != "x" || strings.Join(sources, " ") != "SRC_ENV_VALUE@env" {
+		t.Errorf("got %q from %v", c.Value, sources)
+	}
+
+	// and EnvSource is the environment as a source
+	e.Sources = []Source{Dotenv{}.Source("empty"), EnvSource}
+	c.Value = ""
+	if err := e.Parse(c); err != nil || c.Value != "x" {
+		t.Errorf("got %q, %v", c.Value, err)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	type db struct {
+		Host string `default:"localhost"`
+	}
+	type conf struct {
+		DB      map[string]db `yaml:"db"`
+		Servers []db          `yaml:"servers"`
+	}
+
+	file := Dotenv{"DB_A_HOST": "a", "SERVERS_0_HOST": "s0", "SERVERS_1_HOST": "s1",
+		"SERVERS_01_HOST": "not an index"}
+	if got := strings.Join(file.Enumerate("SERVERS_"), " "); got !=
+		"SERVERS_01_HOST SERVERS_0_HOST SERVERS_1_HOST" {
+		t.Errorf("enumerate: %s", got)
+	}
+
+	// a source without Enumerate is still looked up, but its map
+	// entries cannot be found and lists are probed
+	blind := Dotenv{"DB_B_HOST": "b", "SERVERS_2_HOST": "s2"}
+	e := New()
+	e.Sources = []Source{file.Source("file"), {Name: "blind", Lookup: blind.Lookup}}
+
+	c := &conf{}
+	if err := e.Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.DB) != 1 || c.DB["A"].Host != "a" {
+		t.Errorf("db: %+v", c.DB)
+	}
+	if len(c.Servers) != 3 || c.Servers[2].Host != "s2" {
+		t.Errorf("servers: %+v", c.Servers)
+	}
+
+	// with every source enumerating, lists use it too
+	e.Sources = []Source{file.Source("file"), blind.Source("blind")}
+	c = &conf{}
+	if err := e.Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.DB) != 2 || c.DB["B"].Host != "b" || len(c.Servers) != 3 {
+		t.Errorf("got %+v", c)
+	}
+
+	// nor can an ECP without Enumerate find them
+	e = New()
+	e.LookupValue = file.Lookup
+	e.Enumerate = nil
+	c = &conf{}
+	if err := e.Parse(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.DB != nil || len(c.Servers) != 2 {
+		t.Errorf("got %+v", c)
+	}
+}
+
+// the default Enumerate lists the environment, not what a custom
+// LookupValue reads, so a list of sections is still probed
+func TestEnumerateWithCustomLookup(t *testing.T) {
+	type conf struct {
+		Upstreams []struct{ Host string }
+	}
+	file := Dotenv{"APP_UPSTREAMS_0_HOST": "a", "APP_UPSTREAMS_1_HOST": "b"}
+
+	for name, lookup := range map[string]LookupValueFunc{
+		"dotenv": file.Lookup,
+		"over":   file.Over(lookupValueFromEnv),
+	} {
+		e := New()
+		e.LookupValue = lookup
+		c := &conf{}
+		if err := e.Parse(c, "APP"); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Upstreams) != 2 || c.Upstreams[1].Host != "b" {
+			t.Errorf("%s: got %+v", name, c.Upstreams)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package ecp
 import (
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -13,23 +14,67 @@ type (
 	LookupValueFunc func(key string) (value string, exist bool)
 	// SetValueFunc set the field value and returns whether this filed is set by this function
 	SetValueFunc func(tag reflect.StructTag, field reflect.Value, val string) bool
+	// EnumerateFunc returns every key starting with the prefix
+	EnumerateFunc func(prefix string) []string
 )
 
-const space = " "
+const (
+	space = " "
+	comma = ","
+	equal = "="
+)
 
 // default functions
 var (
-	buildKeyFromEnv = func(structure, field string, tag reflect.StructTag) (key string) {
-		if e := tag.Get("env"); e != "" {
-			return e
+	lookupValueFromEnv = func(key string) (string, bool) { return os.LookupEnv(key) }
+
+	enumerateEnv = func(prefix string) []string {
+		keys := []string{}
+		for _, kv := range os.Environ() {
+			key := strings.SplitN(kv, "=", 2)[0]
+			if key != "" && strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
 		}
-		if structure == "" {
-			key = field
-		} else {
-			key = structure + "_" + field
+		return keys
+	}
+)
+
+// envTag splits the "env" tag into the key and the options following it,
+// `env:"DATABASE_URL,required"`
+func envTag(tag reflect.StructTag) (key string, options []string) {
+	parts := strings.Split(tag.Get("env"), ",")
+	return parts[0], parts[1:]
+}
+
+// envOptions are the options an env tag understands after the key, the
+// other names are aliases
+var envOptions = map[string]bool{"required": true}
+
+// envAliases returns the other names of the key, the older ones of a
+// renamed variable: `env:"DATABASE_HOST,DB_HOST"`
+func envAliases(tag reflect.StructTag) []string {
+	_, options := envTag(tag)
+	aliases := []string{}
+	for _, option := range options {
+		if option != "" && !envOptions[option] {
+			aliases = append(aliases, option)
 		}
-		return strings.ToUpper(key)
 	}
+	return aliases
+}
 
-	lookupValueFromEnv = func(key string) (string, bool) { return os.LookupEnv(key) }
-)
+// isRequired reports whether a field is tagged `required:"true"` or
+// `env:"KEY,required"`
+func isRequired(tag reflect.StructTag) bool {
+	if required, _ := strconv.ParseBool(tag.Get("required")); required {
+		return true
+	}
+	_, options := envTag(tag)
+	for _, option := range options {
+		if option == "required" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package ecp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// unknownKeys returns the variables under the prefix no field of the
+// config is bound to, each with the closest known key as a suggestion,
+// empty when none is close enough. The variables come from Enumerate or
+// the Sources, without them there is nothing to check.
+func (e *ECP) unknownKeys(config interface{}, prefix string) (unknown, suggestions []string) {
+	// the keys of the fields start with the prefix and a separator, the
+	// one BuildKey puts after it
+	under, _, _ := strings.Cut(e.key(prefix, segmentMarker, ""), segmentMarker)
+	names, _ := e.enumerate(under)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := e.keys(config, prefix)
+	matchers := make([]*regexp.Regexp, 0, len(known))
+	for _, f := range known {
+		matchers = append(matchers, keyMatcher(f.Key))
+		for _, alias := range f.Aliases {
+			matchers = append(matchers, keyMatcher(alias))
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if !strings.HasPrefix(name, under) || matchesAny(matchers, name) {
+			continue
+		}
+		unknown = append(unknown, name)
+		suggestions = append(suggestions, closestKey(name, known))
+	}
+	return unknown, suggestions
+}
+
+// keyMatcher matches the key of a field, an index standing for <N> and
+// any entry name for <KEY>
+func keyMatcher(key string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(key)
+	pattern = strings.ReplaceAll(pattern, indexPlaceholder, "[0-9]+")
+	pattern = strings.ReplaceAll(pattern, keyPlaceholder, ".+")
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+func matchesAny(matchers []*regexp.Regexp, name string) bool {
+	for _, re := range matchers {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey returns the known key at the smallest edit distance from
+// name, if it is at most a third of the length of name
+func closestKey(name string, known []Field) string {
+	best, bestDistance := "", len(name)/3+1
+	for _, f := range known {
+		if d := editDistance(name, f.Key); d < bestDistance {
+			best, bestDistance = f.Key, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// unknownError is the error Strict reports an unknown key with
+func unknownError(key, suggestion, source string) error {
+	err := ErrUnknown
+	if suggestion != "" {
+		err = fmt.Errorf("%w, did you mean %s?", ErrUnknown, suggestion)
+	}
+	return &ParseError{Key: key, Source: source, Err: err}
+}
@@ -0,0 +1,74 @@
+package ecp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	type conf struct {
+		Redis struct {
+			Host string
+			Port int
+		} `yaml:"redis"`
+		Upstreams []struct{ Host string } `yaml:"upstreams"`
+		DB        map[string]struct{ Host string }
+	}
+
+	withEnv(t, "UNK_REDIS_HSOT", "typo")
+	withEnv(t, "UNK_REDIS_PORT", "6379")
+	withEnv(t, "UNK_UPSTREAMS_0_HOST", "a")
+	withEnv(t, "UNK_DB_MAIN_HOST", "b")
+	withEnv(t, "UNK_SOMETHING_ELSE_ENTIRELY", "x")
+	withEnv(t, "UNKNOWN", "not under the prefix")
+
+	got := map[string]string{}
+	e := New()
+	e.Advance.OnUnknown = func(key, suggestion string) { got[key] = suggestion }
+
+	c := &conf{}
+	if err := e.Parse(c, "UNK"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Redis.Port != 6379 {
+		t.Errorf("got %+v", c)
+	}
+	if len(got) != 2 || got["UNK_REDIS_HSOT"] != "UNK_REDIS_HOST" ||
+		got["UNK_SOMETHING_ELSE_ENTIRELY"] != "" {
+		t.Errorf("unknown: %v", got)
+	}
+
+	e.Advance.Strict = true
+	err := e.Parse(&conf{}, "UNK")
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 || !errors.Is(err, ErrUnknown) {
+		t.Fatalf("got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "key UNK_REDIS_HSOT: unknown key, did you mean UNK_REDIS_HOST?") {
+		t.Errorf("got %s", msg)
+	}
+
+	// without a prefix, everything in the environment would be unknown
+	if err := e.Parse(&conf{}); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"HOST", "HOST", 0},
+		{"HOST", "HSOT", 2},
+		{"HOST", "HOSTS", 1},
+		{"PORT", "", 4},
+		{"kitten", "sitting", 3},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("%q %q: got %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
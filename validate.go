@@ -0,0 +1,182 @@
+package ecp
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Validator is implemented by a config, or a section of it, to check the
+// rules spanning several of its fields (a certificate needs a key, min has
+// to be lower than max). Its error is wrapped in a ParseError, keyed by
+// the prefix of the section.
+type Validator interface {
+	Validate() error
+}
+
+// compiled "pattern" tags, a config is usually parsed more than once with
+// the same tags
+var patterns sync.Map
+
+// validate checks a converted field against its validation tags:
+//
+//   - min and max bound a number or a duration, and the length of a
+//     string, a slice or a map
+//   - oneof lists the values allowed, separated by spaces
+//   - pattern is a regular expression the whole value has to match
+//
+// oneof and pattern check every element of a slice. A nil pointer has
+// nothing to check.
+func (e *ECP) validate(tag reflect.StructTag, field reflect.Value) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+
+	for _, bound := range []string{"min", "max"} {
+		limit, ok := tag.Lookup(bound)
+		if !ok {
+			continue
+		}
+		if err := e.checkBound(field, bound, limit); err != nil {
+			return err
+		}
+	}
+
+	oneOf, hasOneOf := tag.Lookup("oneof")
+	pattern, hasPattern := tag.Lookup("pattern")
+	if !hasOneOf && !hasPattern {
+		return nil
+	}
+
+	var re *regexp.Regexp
+	if hasPattern {
+		compiled, err := compilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		re = compiled
+	}
+
+	values := []reflect.Value{field}
+	if field.Kind() == reflect.Slice && !unmarshalsText(field.Type()) {
+		values = values[:0]
+		for i := 0; i < field.Len(); i++ {
+			values = append(values, field.Index(i))
+		}
+	}
+	for _, value := range values {
+		v := e.formatValue("", value)
+		if hasOneOf && !contains(strings.Fields(oneOf), v) {
+			return fmt.Errorf("%q is not one of %s", v, oneOf)
+		}
+		if re != nil && !re.MatchString(v) {
+			return fmt.Errorf("%q does not match %s", v, pattern)
+		}
+	}
+	return nil
+}
+
+// checkBound compares a field with the limit of its min or max tag
+func (e *ECP) checkBound(field reflect.Value, bound, limit string) error {
+	if unmarshalsText(field.Type()) {
+		return fmt.Errorf("%s does not apply to %s", bound, field.Type())
+	}
+	badTag := func(err error) error {
+		return fmt.Errorf("bad %s tag %q: %w", bound, limit, err)
+	}
+
+	// where the value stands compared to the limit
+	var below, above bool
+	switch field.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return badTag(err)
+		}
+		if length := field.Len(); (bound == "min" && length < n) || (bound == "max" && length > n) {
+			return fmt.Errorf("length %d is %s %s %d", length, than(bound), bound, n)
+		}
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if field.Type() == durationType {
+			d, err := parseDuration(limit)
+			if err != nil {
+				return badTag(err)
+			}
+			n = int64(d)
+		} else {
+			expanded, err := parseScientific(limit)
+			if err != nil {
+				return badTag(err)
+			}
+			if n, err = strconv.ParseInt(expanded, 10, 64); err != nil {
+				return badTag(err)
+			}
+		}
+		below, above = field.Int() < n, field.Int() > n
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		expanded, err := parseScientific(limit)
+		if err != nil {
+			return badTag(err)
+		}
+		n, err := strconv.ParseUint(expanded, 10, 64)
+		if err != nil {
+			return badTag(err)
+		}
+		below, above = field.Uint() < n, field.Uint() > n
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(limit, 64)
+		if err != nil {
+			return badTag(err)
+		}
+		below, above = field.Float() < f, field.Float() > f
+
+	default:
+		return fmt.Errorf("%s does not apply to %s", bound, field.Type())
+	}
+
+	if (bound == "min" && below) || (bound == "max" && above) {
+		return fmt.Errorf("%s is %s %s %s", e.formatValue("", field), than(bound), bound, limit)
+	}
+	return nil
+}
+
+func than(bound string) string {
+	if bound == "min" {
+		return "less than"
+	}
+	return "greater than"
+}
+
+// compilePattern compiles a pattern tag anchored at both ends, so that it
+// has to match the whole value
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("bad pattern tag %q: %w", pattern, err)
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package ecp
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	type conf struct {
+		Port    int               `env:"VAL_PORT" min:"1" max:"65535" default:"80"`
+		Workers uint8             `env:"VAL_WORKERS" max:"1e2"`
+		Ratio   float64           `env:"VAL_RATIO" min:"0" max:"1"`
+		Timeout time.Duration     `env:"VAL_TIMEOUT" min:"1s" max:"1d"`
+		Name    string            `env:"VAL_NAME" min:"2" max:"8" pattern:"[a-z]+"`
+		Level   string            `env:"VAL_LEVEL" oneof:"debug info warn" default:"info"`
+		Hosts   []string          `env:"VAL_HOSTS" min:"1" pattern:"[a-z.]+"`
+		Modes   []string          `env:"VAL_MODES" oneof:"r w x"`
+		Labels  map[string]string `env:"VAL_LABELS" max:"2"`
+		Limit   *int              `env:"VAL_LIMIT" min:"10"`
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		for k, v := range map[string]string{
+			"VAL_PORT":    "8080",
+			"VAL_WORKERS": "100",
+			"VAL_RATIO":   "0.5",
+			"VAL_TIMEOUT": "1h",
+			"VAL_NAME":    "app",
+			"VAL_HOSTS":   "a.local b.local",
+			"VAL_MODES":   "r w",
+			"VAL_LABELS":  "a=1,b=2",
+			"VAL_LIMIT":   "10",
+		} {
+			withEnv(t, k, v)
+		}
+		c := &conf{}
+		if err := Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Port != 8080 || c.Level != "info" || *c.Limit != 10 {
+			t.Errorf("got %+v", c)
+		}
+	})
+
+	for _, tc := range []struct{ key, value, msg string }{
+		{"VAL_PORT", "0", "less than min"},
+		{"VAL_PORT", "70000", "greater than max"},
+		{"VAL_WORKERS", "101", "greater than max"},
+		{"VAL_RATIO", "1.5", "greater than max"},
+		{"VAL_TIMEOUT", "10ms", "less than min"},
+		{"VAL_TIMEOUT", "2d", "greater than max"},
+		{"VAL_NAME", "a", "length 1 is less than min"},
+		{"VAL_NAME", "APP", "does not match"},
+		{"VAL_LEVEL", "trace", "is not one of"},
+		{"VAL_HOSTS", "ok BAD", "does not match"},
+		{"VAL_MODES", "r z", "is not one of"},
+		{"VAL_LABELS", "a=1,b=2,c=3", "length 3 is greater than max"},
+		{"VAL_LIMIT", "9", "less than min"},
+	} {
+		os.Setenv(tc.key, tc.value)
+		c := &conf{}
+		err := Parse(c)
+		os.Unsetenv(tc.key)
+
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Key != tc.key || perr.Value != tc.value {
+			t.Errorf("%s=%s: expected a ParseError, got %v", tc.key, tc.value, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s=%s: %v", tc.key, tc.value, err)
+		}
+	}
+
+	t.Run("collected and rolled back", func(t *testing.T) {
+		withEnv(t, "VAL_PORT", "0")
+		withEnv(t, "VAL_LEVEL", "trace")
+		e := New()
+		e.Advance.CollectErrors = true
+		c := &conf{Port: 443}
+		var errs Errors
+		if err := e.Parse(c); !errors.As(err, &errs) || len(errs) != 2 {
+			t.Fatalf("expected two errors, got %v", err)
+		}
+		// an invalid value does not stay in the config
+		if c.Port != 443 || c.Level != "" {
+			t.Errorf("got %+v", c)
+		}
+	})
+
+	t.Run("bad tags", func(t *testing.T) {
+		for _, c := range []interface{}{
+			&struct {
+				N int `default:"1" min:"one"`
+			}{},
+			&struct {
+				B bool `default:"true" max:"1"`
+			}{},
+			&struct {
+				S string `default:"x" pattern:"("`
+			}{},
+		} {
+			if err := Parse(c); err == nil {
+				t.Errorf("%T should report its bad tag", c)
+			}
+		}
+	})
+}
+
+type tlsSection struct {
+	Cert string
+	Key  string
+}
+
+func (s tlsSection) Validate() error {
+	if s.Cert != "" && s.Key == "" {
+		return errors.New("a certificate needs a key")
+	}
+	return nil
+}
+
+type rangeSection struct {
+	Min int `default:"1"`
+	Max int `default:"10"`
+}
+
+func (s *rangeSection) Validate() error {
+	if s.Min > s.Max {
+		return errors.New("min is greater than max")
+	}
+	return nil
+}
+
+type ValidatedConf struct {
+	TLS   tlsSection    `yaml:"tls"`
+	Range *rangeSection `yaml:"range"`
+	Extra *tlsSection   `yaml:"extra"`
+
+	calls *[]string
+}
+
+func (c *ValidatedConf) Validate() error {
+	*c.calls = append(*c.calls, "root")
+	if c.Range != nil && c.Range.Max > 100 {
+		return errors.New("too wide")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	calls := []string{}
+	c := &ValidatedConf{calls: &calls}
+	if err := Parse(c, "APP"); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || c.Range == nil || c.Extra != nil {
+		t.Errorf("got %+v after %v", c, calls)
+	}
+
+	for _, tc := range []struct {
+		env  map[string]string
+		key  string
+		path string
+	}{
+		{map[string]string{"APP_TLS_CERT": "cert.pem"}, "APP_TLS", "ValidatedConf.TLS"},
+		{map[string]string{"APP_RANGE_MIN": "20"}, "APP_RANGE", "ValidatedConf.Range"},
+		// an optional section is validated once it exists
+		{map[string]string{"APP_EXTRA_CERT": "cert.pem"}, "APP_EXTRA", "ValidatedConf.Extra"},
+		{map[string]string{"APP_RANGE_MAX": "1000"}, "APP", "ValidatedConf"},
+	} {
+		for k, v := range tc.env {
+			os.Setenv(k, v)
+		}
+		err := Parse(&ValidatedConf{calls: &calls}, "APP")
+		for k := range tc.env {
+			os.Unsetenv(k)
+		}
+
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Key != tc.key || perr.Path != tc.path {
+			t.Errorf("%v: got %v", tc.env, err)
+		}
+	}
+
+	t.Run("bottom-up and collected", func(t *testing.T) {
+		withEnv(t, "APP_TLS_CERT", "cert.pem")
+		withEnv(t, "APP_RANGE_MIN", "20")
+		withEnv(t, "APP_RANGE_MAX", "1000")
+
+		e := New()
+		e.Advance.CollectErrors = true
+		err := e.Parse(&ValidatedConf{calls: &calls}, "APP")
+		var errs Errors
+		if !errors.As(err, &errs) || len(errs) != 2 {
+			t.Fatalf("expected two errors, got %v", err)
+		}
+		want := []string{"key APP_TLS (ValidatedConf.TLS): a certificate needs a key",
+			"key APP (ValidatedConf): too wide"}
+		for i, w := range want {
+			if errs[i].Error() != w {
+				t.Errorf("got %q, want %q", errs[i], w)
+			}
+		}
+	})
+}
+
+type incompleteConf struct {
+	TLS   tlsSection   `yaml:"tls"`
+	Token string       `required:"true"`
+	Range rangeSection `yaml:"range"`
+}
+
+func (incompleteConf) Validate() error {
+	return errors.New("never valid")
+}
+
+// a failed rule does not hide a missing key, and the root missing one is
+// not validated
+func TestValidatorMissing(t *testing.T) {
+	withEnv(t, "APP_TLS_CERT", "cert.pem")
+	withEnv(t, "APP_RANGE_MIN", "20")
+
+	err := Parse(&incompleteConf{}, "APP")
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected three errors, got %v", err)
+	}
+	want := []string{"key APP_TLS (incompleteConf.TLS): a certificate needs a key",
+		"key APP_TOKEN (incompleteConf.Token): required but not set",
+		"key APP_RANGE (incompleteConf.Range): min is greater than max"}
+	for i, w := range want {
+		if errs[i].Error() != w {
+			t.Errorf("got %q, want %q", errs[i], w)
+		}
+	}
+
+	withEnv(t, "APP_TOKEN", "t")
+	withEnv(t, "APP_TLS_KEY", "key.pem")
+	withEnv(t, "APP_RANGE_MIN", "1")
+	if err := Parse(&incompleteConf{}, "APP"); err == nil || err.Error() != "key APP (incompleteConf): never valid" {
+		t.Errorf("got %v", err)
+	}
+}
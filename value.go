@@ -1,14 +1,28 @@
 package ecp
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var durationType = reflect.TypeOf(time.Duration(0))
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unmarshalsText reports whether a value of this type, or a pointer to
+// one, implements encoding.TextUnmarshaler. Such a type is a single value
+// whatever its kind: a net.IP is not a slice of bytes and a big.Int is not
+// a config section.
+func unmarshalsText(typ reflect.Type) bool {
+	return typ.Implements(textUnmarshalerType) ||
+		reflect.PtrTo(typ).Implements(textUnmarshalerType)
+}
 
 // setValue sets a single scalar value from its string form.
 //
@@ -16,7 +30,16 @@ var durationType = reflect.TypeOf(time.Duration(0))
 // named types (type Level int, type Name string, time.Duration, ...) are
 // handled like their underlying kind instead of panicking on an
 // unassignable concrete type such as []int -> []Level.
+//
+// A type implementing encoding.TextUnmarshaler converts itself, its kind
+// is not even looked at.
 func setValue(field reflect.Value, v string) error {
+	if field.CanAddr() {
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(v))
+		}
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(v)
@@ -71,7 +94,8 @@ func setValue(field reflect.Value, v string) error {
 
 // expandNumber rewrites the scientific and thousand-separator notation
 // ("1e3", "1,000") into a plain integer literal. Duration fields keep
-// their own syntax, and non integer kinds are returned untouched.
+// their own syntax, and so do the types implementing
+// encoding.TextUnmarshaler. Non integer kinds are returned untouched.
 //
 // It is deliberately not applied to slice elements: there, "1,2" is much
 // more likely to be a wrong separator than the number 12.
@@ -79,7 +103,7 @@ func expandNumber(typ reflect.Type, v string) (string, error) {
 	switch typ.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if typ == durationType {
+		if typ == durationType || unmarshalsText(typ) {
 			return v, nil
 		}
 		return parseScientific(v)
@@ -87,18 +111,131 @@ func expandNumber(typ reflect.Type, v string) (string, error) {
 	return v, nil
 }
 
-// canSetKind reports whether a field of this kind can be filled from a
-// string value at all. Unsupported kinds (map, array, chan, ...) are
-// reported so that they can be skipped when listing and rejected with a
-// clear error when a value is actually provided for them.
-func canSetKind(kind reflect.Kind) bool {
-	switch kind {
+// canSetType reports whether a field of this type can be filled from a
+// string value at all. Unsupported types (array, chan, func, a map of
+// anything but single values, ...) are reported so that they can be
+// skipped when listing and rejected with a clear error when a value is
+// actually provided for them.
+func canSetType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Struct:
+		return true
+	case reflect.Ptr:
+		return unmarshalsText(typ) || canSetType(typ.Elem())
+	case reflect.Map:
+		// parseMap goes through setValue for both
+		return isScalar(typ.Key()) && isScalar(typ.Elem())
+	}
+	return isScalar(typ)
+}
+
+// isScalar reports whether setValue can fill a value of this type
+func isScalar(typ reflect.Type) bool {
+	if unmarshalsText(typ) {
+		return true
+	}
+	switch typ.Kind() {
 	case reflect.String, reflect.Bool,
 		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64,
-		reflect.Slice, reflect.Ptr, reflect.Struct:
+		reflect.Float32, reflect.Float64:
 		return true
 	}
 	return false
 }
+
+// formatDefault renders a default value through the field's own
+// encoding.TextMarshaler, so that a listing shows the canonical form of
+// it ("INFO" for a level that marshals to "info" is listed as "info").
+// A value that does not round trip is returned untouched, Parse is the
+// one reporting it.
+func formatDefault(typ reflect.Type, v string) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if v == "" || !unmarshalsText(typ) {
+		return v
+	}
+
+	// a *T has the methods of both T and *T
+	value := reflect.New(typ).Interface()
+	if err := value.(encoding.TextUnmarshaler).UnmarshalText([]byte(v)); err != nil {
+		return v
+	}
+	m, ok := value.(encoding.TextMarshaler)
+	if !ok {
+		return v
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		return v
+	}
+	return string(text)
+}
+
+// formatValue writes the value of a field in the syntax Parse reads it
+// in: slices joined with the separator of the field (see splitChar),
+// maps as "a=1,b=2", durations as "1m0s" and text types through their
+// encoding.TextMarshaler. A nil pointer is an empty string.
+func (e *ECP) formatValue(tag reflect.StructTag, field reflect.Value) string {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return ""
+		}
+		field = field.Elem()
+	}
+
+	// MarshalText often has a pointer receiver (big.Int), and map values
+	// are not addressable
+	if !field.CanAddr() {
+		addressable := reflect.New(field.Type()).Elem()
+		addressable.Set(field)
+		field = addressable
+	}
+	if m, ok := field.Addr().Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String()
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == durationType {
+			return time.Duration(field.Int()).String()
+		}
+		return strconv.FormatInt(field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits())
+
+	case reflect.Slice:
+		sep := e.splitChar(tag)
+		parts := make([]string, field.Len())
+		for i := range parts {
+			parts[i] = e.formatValue("", field.Index(i))
+			if e.Advance.QuotedSlices {
+				parts[i] = quoteElement(parts[i], sep)
+			}
+		}
+		return strings.Join(parts, sep)
+
+	case reflect.Map:
+		entrySep, pairSep := e.mapSeparators()
+		entries := make([]string, 0, field.Len())
+		iter := field.MapRange()
+		for iter.Next() {
+			entries = append(entries,
+				e.formatValue("", iter.Key())+pairSep+e.formatValue("", iter.Value()))
+		}
+		// map order is random, a report or a listing should not be
+		sort.Strings(entries)
+		return strings.Join(entries, entrySep)
+	}
+
+	return fmt.Sprint(field.Interface())
+}
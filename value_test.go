@@ -0,0 +1,97 @@
+package ecp
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// textLevel is an enum that converts itself, the way log levels usually do
+type textLevel int
+
+func (l *textLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func (l textLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"debug", "info"}[l]), nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	type conf struct {
+		Level  textLevel         `default:"INFO"`
+		Levels []textLevel       `default:"debug info"`
+		IP     net.IP            `default:"127.0.0.1"`
+		IPs    []net.IP          `env:"TEXT_IPS"`
+		PIP    *net.IP           `env:"TEXT_PIP"`
+		Big    big.Int           `env:"TEXT_BIG"`
+		PBig   *big.Int          `env:"TEXT_PBIG"`
+		Since  time.Time         `env:"TEXT_SINCE"`
+		Named  map[string]net.IP `env:"TEXT_NAMED"`
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		withEnv(t, "TEXT_IPS", "10.0.0.1 10.0.0.2")
+		withEnv(t, "TEXT_PIP", "::1")
+		withEnv(t, "TEXT_BIG", "123456789012345678901234567890")
+		withEnv(t, "TEXT_PBIG", "42")
+		withEnv(t, "TEXT_SINCE", "2020-01-02T03:04:05Z")
+		withEnv(t, "TEXT_NAMED", "gw=10.0.0.254")
+
+		c := &conf{}
+		if err := Parse(c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Level != 1 || len(c.Levels) != 2 || c.Levels[1] != 1 {
+			t.Errorf("levels: %v %v", c.Level, c.Levels)
+		}
+		if !c.IP.Equal(net.IPv4(127, 0, 0, 1)) || len(c.IPs) != 2 {
+			t.Errorf("ips: %v %v", c.IP, c.IPs)
+		}
+		if c.PIP == nil || !c.PIP.Equal(net.IPv6loopback) {
+			t.Errorf("pointer ip: %v", c.PIP)
+		}
+		if c.Big.String() != "123456789012345678901234567890" {
+			t.Errorf("big: %s", c.Big.String())
+		}
+		if c.PBig == nil || c.PBig.Int64() != 42 {
+			t.Errorf("pointer big: %v", c.PBig)
+		}
+		if c.Since.Year() != 2020 {
+			t.Errorf("time: %v", c.Since)
+		}
+		if !c.Named["gw"].Equal(net.IPv4(10, 0, 0, 254)) {
+			t.Errorf("map: %v", c.Named)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		withEnv(t, "TEXT_BIG", "not a number")
+		if err := Parse(&conf{}); err == nil {
+			t.Error("expected an error from UnmarshalText")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list := strings.Join(List(conf{}), " ")
+		// the default is shown the way MarshalText writes it, and big.Int
+		// is a single key rather than a section without any field
+		for _, want := range []string{"LEVEL=info", "LEVELS=\"debug info\"",
+			"IP=127.0.0.1", "TEXT_BIG=", "TEXT_PBIG="} {
+			if !strings.Contains(list, want) {
+				t.Errorf("missing %s in %s", want, list)
+			}
+		}
+	})
+}